@@ -75,3 +75,18 @@ func BenchmarkCompareReadYaml(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkAppendEntries(b *testing.B) {
+	entries := make([]*Entry, 1000)
+	for i := range entries {
+		entries[i] = &Entry{Key: []string{"a"}, Val: "1"}
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := &Document{}
+		for _, e := range entries {
+			d.AppendEntry(e)
+		}
+	}
+}
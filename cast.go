@@ -0,0 +1,110 @@
+package keyval
+
+import "io"
+
+// StateApplier implements CAST, Continuously Applied State Transfer: it consumes a stream of entries, and
+// applies them to a state document, where every key has a single, effective entry.
+//
+// An entry with a key sets the value and the comment of the key, replacing the previous entry, or, when the
+// key was not present, appending a new one. A deleted entry, and, when Tombstone is not empty, an entry having
+// it as the value, deletes the key from the state. Entries without a key, e.g. comment-only entries, don't
+// change the state.
+//
+// The changes of the state can be observed with Subscribe, and the state can be read concurrently with the
+// applying of the entries.
+type StateApplier struct {
+	Tombstone string
+	state     *SyncDocument
+}
+
+// NewStateApplier creates an applier with d as the initial state. When d is nil, the initial state is empty.
+// When d has shadowed entries, they are discarded on the first entry applied with the same key. The applier
+// takes over d, it must not be used directly anymore.
+func NewStateApplier(d *Document) *StateApplier {
+	return &StateApplier{state: NewSyncDocument(d)}
+}
+
+func (a *StateApplier) tombstone(e *Entry) bool {
+	return e.Deleted || a.Tombstone != "" && e.Val == a.Tombstone
+}
+
+func (a *StateApplier) apply(d *Document, e *Entry) {
+	if e == nil || len(e.Key) == 0 {
+		return
+	}
+
+	if a.tombstone(e) {
+		d.DeleteOf(e.Key...)
+		return
+	}
+
+	entries := d.EntriesOf(e.Key...)
+	if len(entries) == 0 || entries[len(entries)-1].Deleted {
+		d.DeleteEntry(entries...)
+		d.AppendEntry(copyEntry(e))
+		return
+	}
+
+	last := entries[len(entries)-1]
+	d.DeleteEntry(entries[:len(entries)-1]...)
+	d.setField(last, &last.Val, e.Val)
+	d.setField(last, &last.Comment, e.Comment)
+}
+
+// Apply applies the entries to the state, in a single transaction.
+func (a *StateApplier) Apply(e ...*Entry) {
+	a.state.Write(func(d *Document) error {
+		for _, ei := range e {
+			a.apply(d, ei)
+		}
+
+		return nil
+	})
+}
+
+// ApplyAll reads the entries from r, and applies them one by one, until r is exhausted. It returns nil when
+// the stream ended with io.EOF, otherwise the read error. The entries applied before the error are kept.
+func (a *StateApplier) ApplyAll(r EntrySource) error {
+	for {
+		e, err := r.ReadEntry()
+		if e != nil {
+			a.Apply(e)
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Snapshot returns the current effective state.
+func (a *StateApplier) Snapshot() *Snapshot {
+	return a.state.Snapshot()
+}
+
+// State returns the state document for concurrent access.
+func (a *StateApplier) State() *SyncDocument {
+	return a.state
+}
+
+// Subscribe calls f on every change of the state under prefix. The callbacks are called synchronously while
+// applying the entries, holding the write lock of the state, so they must not access the applier. The returned
+// function cancels the subscription.
+func (a *StateApplier) Subscribe(f func(Change), prefix ...string) func() {
+	var cancel func()
+	a.state.Write(func(d *Document) error {
+		cancel = d.Subscribe(f, prefix...)
+		return nil
+	})
+
+	return func() {
+		a.state.Write(func(*Document) error {
+			cancel()
+			return nil
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package keyval
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStateApplier(t *testing.T) {
+	d := &Document{}
+	d.Append("a", "0")
+	d.Append("a", "1")
+	a := NewStateApplier(d)
+	a.Tombstone = "-"
+
+	var changes []Change
+	a.Subscribe(func(c Change) { changes = append(changes, c) })
+
+	stream := `a = 2
+# about b
+b = 3
+c = 4
+# closing comment`
+	if err := a.ApplyAll(NewEntryReader(bytes.NewBufferString(stream))); err != nil {
+		t.Error(err)
+		return
+	}
+
+	s := a.Snapshot()
+	if s.Len() != 3 || s.Val("a") != "2" || s.Val("b") != "3" || s.Comment("b") != "about b" {
+		t.Error("invalid state", s.Entries())
+	}
+
+	types := []ChangeType{ChangeDelete, ChangeVal, ChangeInsert, ChangeInsert}
+	if len(changes) != len(types) {
+		t.Error("invalid changes", changes)
+		return
+	}
+
+	for i, c := range changes {
+		if c.Type != types[i] {
+			t.Error(i, "invalid change", c)
+		}
+	}
+
+	changes = nil
+	a.Apply(&Entry{Key: []string{"b"}, Val: "-"}, &Entry{Key: []string{"c"}, Val: "4", Comment: "about b"}, &Entry{Comment: "x"})
+	if len(changes) != 1 || changes[0].Type != ChangeDelete || JoinKey(changes[0].Key) != "b" {
+		t.Error("invalid changes", changes)
+	}
+
+	s = a.Snapshot()
+	if _, ok := s.Lookup("b"); ok || s.Len() != 2 {
+		t.Error("failed to delete", s.Entries())
+	}
+}
+
+func TestStateApplierError(t *testing.T) {
+	a := NewStateApplier(nil)
+	err := a.ApplyAll(NewEntryReader(strings.NewReader("a = 1\n[b")))
+	if err == nil {
+		t.Error("failed to fail")
+	}
+
+	if a.Snapshot().Val("a") != "1" {
+		t.Error("failed to keep applied entries")
+	}
+}
+
+func TestStateApplierDeleted(t *testing.T) {
+	a := NewStateApplier(nil)
+	if err := a.ApplyAll(NewEntryReader(strings.NewReader("a = 1\nb = 2\na = \\-\n"))); err != nil {
+		t.Error(err)
+		return
+	}
+
+	s := a.Snapshot()
+	if _, ok := s.Lookup("a"); ok || s.Len() != 1 {
+		t.Error("failed to delete", s.Entries())
+	}
+}
+
+func TestStateApplierInitialDeleted(t *testing.T) {
+	d := &Document{}
+	d.Append("a", "1")
+	d.AppendDeleted("a")
+	a := NewStateApplier(d)
+	a.Apply(&Entry{Key: []string{"a"}, Val: "2"})
+
+	s := a.Snapshot()
+	if v, ok := s.Lookup("a"); !ok || v != "2" || s.Len() != 1 {
+		t.Error("failed to set deleted key", s.Entries())
+	}
+}
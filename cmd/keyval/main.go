@@ -2,6 +2,8 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/aryszka/keyval"
 	"io"
@@ -10,6 +12,11 @@ import (
 	"strings"
 )
 
+var (
+	errUsage   = errors.New("usage: keyval [get <pattern> [file] | validate -schema <schema> [file]]")
+	errInvalid = errors.New("document invalid")
+)
+
 func printKeyVal(kv *keyval.Entry) {
 	key := strings.Join(kv.Key, ".")
 	fmt.Printf("# %s\n%s: %s\n\n", kv.Comment, key, kv.Val)
@@ -67,8 +74,106 @@ func writeJson() error {
 	return write(w, nil, m)
 }
 
+func readDocument(args []string) (*keyval.Document, error) {
+	in := io.Reader(os.Stdin)
+	if len(args) > 0 {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		defer f.Close()
+		in = f
+	}
+
+	d := &keyval.Document{}
+	if err := d.ReadAll(in); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func get(args []string) error {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return errUsage
+	}
+
+	d, err := readDocument(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+
+	entries, err := d.Query(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	w := keyval.NewEntryWriter(os.Stdout)
+	for _, e := range entries {
+		if err := w.WriteEntry(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	schemaFile := fs.String("schema", "", "schema file")
+	fs.Parse(args)
+	if *schemaFile == "" || fs.NArg() > 1 {
+		return errUsage
+	}
+
+	f, err := os.Open(*schemaFile)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+	s, err := keyval.ReadSchema(f)
+	if err != nil {
+		return err
+	}
+
+	d, err := readDocument(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	err = d.Validate(s)
+	if violations, ok := err.(keyval.ValidationError); ok {
+		for _, v := range violations {
+			fmt.Println(v)
+		}
+
+		return errInvalid
+	}
+
+	return err
+}
+
 func main() {
-	if err := read(); err != nil {
+	var err error
+	args := os.Args[1:]
+	if len(args) == 0 {
+		err = read()
+	} else {
+		switch args[0] {
+		case "get":
+			err = get(args[1:])
+		case "validate":
+			err = validate(args[1:])
+		default:
+			err = errUsage
+		}
+	}
+
+	if err != nil {
 		log.Fatal(err)
 	}
 }
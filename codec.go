@@ -1,12 +1,223 @@
 package keyval
 
-import "io"
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Encoder struct{}
 
-type Decoder struct{}
+type Decoder struct{ reader io.Reader }
+
+var (
+	ErrDecodeTarget = errors.New("decode target must be a non-nil pointer to a struct")
+	ErrUnsupported  = errors.New("unsupported field type")
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
 
 func NewEncoder(w io.Writer) *Encoder         { return nil }
 func (e *Encoder) Encode(v interface{}) error { return nil }
-func NewDecoder(r io.Reader) *Decoder         { return nil }
-func (d *Decoder) Decode(v interface{}) error { return nil }
+func NewDecoder(r io.Reader) *Decoder         { return &Decoder{reader: r} }
+
+func (d *Decoder) Decode(v interface{}) error {
+	doc := &Document{}
+	if err := doc.ReadAll(d.reader); err != nil && err != io.EOF {
+		return err
+	}
+
+	return doc.Decode(v)
+}
+
+func setScalar(v reflect.Value, s string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		v.SetInt(int64(d))
+		return err
+	case v.Type() == timeType:
+		t, err := time.Parse(time.RFC3339, s)
+		v.Set(reflect.ValueOf(t))
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := parseBool(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	default:
+		return ErrUnsupported
+	}
+
+	return nil
+}
+
+func fieldKey(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("keyval")
+	if tag == "-" {
+		return "", false
+	}
+
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+
+	return name, true
+}
+
+func tagRequired(tag reflect.StructTag) bool {
+	for _, o := range strings.Split(tag.Get("keyval"), ",")[1:] {
+		if o == "required" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decoder collects the missing required keys while decoding a document
+type decoder struct {
+	doc     *Document
+	missing ValidationError
+}
+
+// field sets the field from the entries with the key, or from the default tag
+func (dc *decoder) field(key []string, tag reflect.StructTag, v reflect.Value) error {
+	required := tagRequired(tag)
+	if v.Kind() == reflect.Struct && v.Type() != timeType {
+		return dc.structFields(key, v)
+	}
+
+	if v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct && v.Type().Elem() != timeType {
+		if len(dc.doc.KeysOf(key...)) == 0 && !required {
+			return nil
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return dc.structFields(key, v.Elem())
+	}
+
+	vals := dc.doc.ValsOf(key...)
+	if len(vals) == 0 {
+		if def, ok := tag.Lookup("default"); ok {
+			vals = []string{def}
+			if v.Kind() == reflect.Slice {
+				vals = strings.Split(def, ",")
+			}
+		}
+	}
+
+	if len(vals) == 0 {
+		if required {
+			dc.missing = append(dc.missing, Violation{Key: key, Message: "missing required key"})
+		}
+
+		return nil
+	}
+
+	if v.Kind() == reflect.Slice {
+		s := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, vi := range vals {
+			if err := setScalar(s.Index(i), strings.TrimSpace(vi)); err != nil {
+				return &KeyError{Key: key, Val: vi, Err: err}
+			}
+		}
+
+		v.Set(s)
+		return nil
+	}
+
+	val := vals[len(vals)-1]
+	if err := setScalar(v, val); err != nil {
+		return &KeyError{Key: key, Val: val, Err: err}
+	}
+
+	return nil
+}
+
+func (dc *decoder) structFields(prefix []string, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, ok := fieldKey(f)
+		if !ok {
+			continue
+		}
+
+		key := append(append([]string(nil), prefix...), name)
+		if f.Anonymous && f.Tag.Get("keyval") == "" && f.Type.Kind() == reflect.Struct {
+			key = prefix
+		}
+
+		if err := dc.field(key, f.Tag, v.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Decode sets the fields of the struct pointed by v from the effective values of the document. The key of a
+// field is taken from the keyval tag, or, if not set, from the lowercase field name. Nested structs take the
+// entries below their key, and slices take the values of the repeated keys. The tag options are 'required'
+// and the separate default tag, e.g. `keyval:"port,required"` or `default:"8080"`. The missing required keys
+// are reported together in a ValidationError.
+func (d *Document) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrDecodeTarget
+	}
+
+	dc := &decoder{doc: d}
+	if err := dc.structFields(nil, rv.Elem()); err != nil {
+		return err
+	}
+
+	if len(dc.missing) > 0 {
+		return dc.missing
+	}
+
+	return nil
+}
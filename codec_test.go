@@ -0,0 +1,125 @@
+package keyval
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type testHTTPConfig struct {
+	Port    int           `keyval:"port,required"`
+	Host    string        `default:"localhost"`
+	Timeout time.Duration `default:"3s"`
+	Hosts   []string      `keyval:"backend"`
+}
+
+type testConfig struct {
+	Name    string `keyval:",required"`
+	Debug   bool
+	Ratio   float64
+	Started time.Time
+	Ignored string `keyval:"-"`
+	HTTP    testHTTPConfig
+	Cache   *struct {
+		Size uint64 `keyval:"size"`
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var c testConfig
+	err := NewDecoder(bytes.NewBufferString(`
+name = test
+debug = true
+ratio = 0.5
+started = 2016-03-01T12:00:00Z
+ignored = value
+
+[http]
+port = 8080
+backend = a.example.org
+backend = b.example.org`)).Decode(&c)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if c.Name != "test" || !c.Debug || c.Ratio != 0.5 || c.Ignored != "" ||
+		!c.Started.Equal(time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Error("failed to decode", c)
+	}
+
+	if c.HTTP.Port != 8080 || c.HTTP.Host != "localhost" || c.HTTP.Timeout != 3*time.Second {
+		t.Error("failed to decode nested struct", c.HTTP)
+	}
+
+	if len(c.HTTP.Hosts) != 2 || c.HTTP.Hosts[1] != "b.example.org" {
+		t.Error("failed to decode slice", c.HTTP.Hosts)
+	}
+
+	if c.Cache != nil {
+		t.Error("failed to skip missing pointer struct")
+	}
+}
+
+func TestDecodeMissingRequired(t *testing.T) {
+	var c testConfig
+	err := NewDecoder(bytes.NewBufferString("debug = true")).Decode(&c)
+	missing, ok := err.(ValidationError)
+	if !ok || len(missing) != 2 {
+		t.Error("failed to report missing keys", err)
+		return
+	}
+
+	if JoinKey(missing[0].Key) != "name" || JoinKey(missing[1].Key) != "http.port" {
+		t.Error("invalid missing keys", missing)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var c testConfig
+	err := NewDecoder(bytes.NewBufferString("name = test\n[http]\nport = eighty")).Decode(&c)
+	if kerr, ok := err.(*KeyError); !ok || JoinKey(kerr.Key) != "http.port" {
+		t.Error("failed to fail", err)
+	}
+
+	if err := (&Document{}).Decode(c); err != ErrDecodeTarget {
+		t.Error("failed to fail", err)
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	s, err := ReadSchema(bytes.NewBufferString(`
+[server/port]
+type = int
+default = 8080
+required = true
+
+[server/host]
+default = localhost
+
+[servers/*/port]
+default = 9000`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	d := &Document{}
+	d.Append("server.host", "example.org")
+	applied := d.ApplyDefaults(s)
+	if len(applied) != 1 || d.Val("server.port") != "8080" || d.Val("server.host") != "example.org" {
+		t.Error("failed to apply defaults")
+	}
+
+	if !d.IsDefault(applied[0]) || d.IsDefault(d.EntryAt(0)) {
+		t.Error("failed to mark defaults")
+	}
+
+	if err := d.Validate(s); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := ReadSchema(bytes.NewBufferString("[server/port]\ntype = int\ndefault = eighty")); err == nil {
+		t.Error("failed to fail")
+	}
+}
@@ -0,0 +1,83 @@
+package keyval
+
+// CommentBlock is a range of consecutive entries sharing the same comment. When written, the comment of a
+// block appears only once, before the first entry of the block.
+type CommentBlock struct {
+	Comment string
+	At      int
+	Len     int
+	Entries []*Entry
+}
+
+func (d *Document) CommentBlocks() []CommentBlock {
+	var (
+		blocks  []CommentBlock
+		current *CommentBlock
+	)
+
+	for i, e := range d.Entries() {
+		if e == nil {
+			if current != nil {
+				current.Len++
+			}
+
+			continue
+		}
+
+		if current == nil || current.Comment != e.Comment {
+			blocks = append(blocks, CommentBlock{Comment: e.Comment, At: i})
+			current = &blocks[len(blocks)-1]
+		}
+
+		current.Len++
+		current.Entries = append(current.Entries, e)
+	}
+
+	return blocks
+}
+
+// CommentBlockAt returns the comment block containing the entry at position i.
+func (d *Document) CommentBlockAt(i int) (CommentBlock, bool) {
+	for _, b := range d.CommentBlocks() {
+		if i >= b.At && i < b.At+b.Len {
+			return b, true
+		}
+	}
+
+	return CommentBlock{}, false
+}
+
+// CommentBlockOf returns the comment block containing the effective entry of the key.
+func (d *Document) CommentBlockOf(key ...string) (CommentBlock, bool) {
+	i, _ := d.EntryOf(key...)
+	return d.CommentBlockAt(i)
+}
+
+// SetBlockCommentAt sets the comment of all the entries in the comment block containing the entry at position
+// i.
+func (d *Document) SetBlockCommentAt(i int, comment string) {
+	b, ok := d.CommentBlockAt(i)
+	if !ok {
+		return
+	}
+
+	d.Begin()
+	defer d.Commit()
+
+	for _, e := range b.Entries {
+		d.setField(e, &e.Comment, comment)
+	}
+}
+
+func (d *Document) SetBlockCommentOf(key []string, comment string) {
+	i, _ := d.EntryOf(key...)
+	d.SetBlockCommentAt(i, comment)
+}
+
+func (d *Document) CommentBlock(key string) (CommentBlock, bool) {
+	return d.CommentBlockOf(SplitKey(key)...)
+}
+
+func (d *Document) SetBlockComment(key string, comment string) {
+	d.SetBlockCommentOf(SplitKey(key), comment)
+}
@@ -0,0 +1,91 @@
+package keyval
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+const commentTestDoc = `# server settings
+[server]
+host = example.org
+port = 8080
+
+# client settings
+[client]
+timeout = 3s
+`
+
+func TestCommentBlocks(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(commentTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+
+	blocks := d.CommentBlocks()
+	if len(blocks) != 2 {
+		t.Error("invalid number of blocks", len(blocks))
+		return
+	}
+
+	if blocks[0].Comment != "server settings" || blocks[0].At != 0 || blocks[0].Len != 2 || len(blocks[0].Entries) != 2 {
+		t.Error("invalid block", blocks[0])
+	}
+
+	if b, ok := d.CommentBlock("client.timeout"); !ok || b.Comment != "client settings" || b.At != 2 {
+		t.Error("invalid block", b)
+	}
+
+	if _, ok := d.CommentBlock("client.missing"); ok {
+		t.Error("failed to fail")
+	}
+}
+
+func TestInsertIntoCommentBlock(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(commentTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+
+	d.Insert(1, "server.tls", "true")
+	if d.Comment("server.tls") != "server settings" {
+		t.Error("failed to inherit comment")
+	}
+
+	d.Insert(0, "name", "test")
+	if d.Comment("name") != "" {
+		t.Error("invalid comment")
+	}
+
+	d.Append("cache.size", "10")
+	if d.Comment("cache.size") != "" {
+		t.Error("invalid comment")
+	}
+
+	d.Insert(d.Index(d.EntriesOf("client", "timeout")[0]), "server.timeout", "1s")
+	if d.Comment("server.timeout") != "" {
+		t.Error("invalid comment")
+	}
+}
+
+func TestSetBlockComment(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(commentTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+
+	d.SetBlockComment("server.port", "http server")
+
+	buf := bytes.NewBuffer(nil)
+	if err := d.WriteAll(buf); err != nil {
+		t.Error(err)
+		return
+	}
+
+	expect := "# http server\n[server]\nhost = example.org\nport = 8080\n\n" +
+		"# client settings\n[client]\ntimeout = 3s\n"
+	if buf.String() != expect {
+		t.Error("failed to set block comment")
+		t.Log(buf.String())
+	}
+}
@@ -0,0 +1,142 @@
+package keyval
+
+// CompactionPolicy defines when a document is compacted automatically. Compaction discards the shadowed
+// entries, the same way as TruncateEffective, so the limits are not enforced when the effective entries alone
+// exceed them. Zero values disable the given limit.
+type CompactionPolicy struct {
+	MaxEntries       int
+	MaxBytes         int
+	MaxShadowedRatio float64
+}
+
+type CompactionStats struct {
+	Compactions    int
+	Discarded      int
+	DiscardedBytes int
+}
+
+type compaction struct {
+	policy     CompactionPolicy
+	stats      CompactionStats
+	keys       map[string]int
+	size       int
+	compacting bool
+}
+
+// the approximate size of an entry in memory, not counting the overhead
+func entrySize(e *Entry) int {
+	if e == nil {
+		return 0
+	}
+
+	s := len(e.Val) + len(e.Comment)
+	for _, k := range e.Key {
+		s += len(k)
+	}
+
+	return s
+}
+
+func (c *compaction) add(e *Entry) {
+	if e != nil {
+		c.keys[canonicalKey(e.Key)]++
+		c.size += entrySize(e)
+	}
+}
+
+func (c *compaction) remove(e *Entry) {
+	if e == nil {
+		return
+	}
+
+	ks := canonicalKey(e.Key)
+	if c.keys[ks]--; c.keys[ks] <= 0 {
+		delete(c.keys, ks)
+	}
+
+	c.size -= entrySize(e)
+}
+
+// SetCompaction enables automatic compaction. The policy is checked after every change made outside of a
+// transaction, and when the outermost transaction is committed.
+func (d *Document) SetCompaction(p CompactionPolicy) {
+	c := &compaction{policy: p, keys: make(map[string]int)}
+	for _, e := range d.entries {
+		c.add(e)
+	}
+
+	d.compaction = c
+	d.autoCompact()
+}
+
+func (d *Document) DisableCompaction() {
+	d.compaction = nil
+}
+
+func (d *Document) CompactionStats() CompactionStats {
+	if d.compaction == nil {
+		return CompactionStats{}
+	}
+
+	return d.compaction.stats
+}
+
+func (d *Document) shadowed() int {
+	return d.Len() - len(d.compaction.keys)
+}
+
+func (d *Document) needsCompaction() bool {
+	c := d.compaction
+	if c == nil || c.compacting || len(d.tx) > 0 || d.shadowed() == 0 {
+		return false
+	}
+
+	p := c.policy
+	return p.MaxEntries > 0 && d.Len() > p.MaxEntries ||
+		p.MaxBytes > 0 && c.size > p.MaxBytes ||
+		p.MaxShadowedRatio > 0 && float64(d.shadowed())/float64(d.Len()) > p.MaxShadowedRatio
+}
+
+// autoCompact compacts the document when the policy requires it. With history enabled, the compaction is
+// merged into the last step, so that undoing it restores the state before the change that triggered it.
+func (d *Document) autoCompact() {
+	if !d.needsCompaction() {
+		return
+	}
+
+	if d.history == nil {
+		d.Compact()
+		return
+	}
+
+	d.Begin()
+	d.Compact()
+	ops, _ := d.popTx()
+	if h := d.history; len(h.done) > 0 {
+		h.done[len(h.done)-1] = append(h.done[len(h.done)-1], ops...)
+	}
+}
+
+// Compact discards the shadowed entries, and returns their number.
+func (d *Document) Compact() int {
+	c := d.compaction
+	if c == nil {
+		n := d.Len()
+		d.TruncateEffective()
+		return n - d.Len()
+	}
+
+	c.compacting = true
+	defer func() { c.compacting = false }()
+
+	n, size := d.Len(), c.size
+	d.TruncateEffective()
+	discarded := n - d.Len()
+	if discarded > 0 {
+		c.stats.Compactions++
+		c.stats.Discarded += discarded
+		c.stats.DiscardedBytes += size - c.size
+	}
+
+	return discarded
+}
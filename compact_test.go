@@ -0,0 +1,124 @@
+package keyval
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestCompactMaxEntries(t *testing.T) {
+	d := &Document{}
+	d.SetCompaction(CompactionPolicy{MaxEntries: 4})
+	for i := 0; i < 10; i++ {
+		d.Append("a", strconv.Itoa(i))
+		d.Append("b", strconv.Itoa(i))
+	}
+
+	if d.Len() > 4 {
+		t.Error("failed to compact", d.Len())
+	}
+
+	if d.Val("a") != "9" || d.Val("b") != "9" {
+		t.Error("failed to keep effective state")
+	}
+
+	stats := d.CompactionStats()
+	if stats.Compactions == 0 || stats.Discarded != 20-d.Len() {
+		t.Error("invalid stats", stats)
+	}
+}
+
+func TestCompactKeepsEffectiveState(t *testing.T) {
+	d := &Document{}
+	d.SetCompaction(CompactionPolicy{MaxEntries: 2})
+	d.Append("a", "1")
+	d.Append("b", "2")
+	d.Append("c", "3")
+	if d.Len() != 3 || d.CompactionStats().Compactions != 0 {
+		t.Error("failed to keep effective state")
+	}
+}
+
+func TestCompactMaxBytes(t *testing.T) {
+	d := &Document{}
+	d.Append("a", "1234567890")
+	d.Append("a", "1234567890")
+	d.SetCompaction(CompactionPolicy{MaxBytes: 22})
+	if d.Len() != 2 {
+		t.Error("compacted too early")
+	}
+
+	d.SetVal("a", "12345678901")
+	if d.Len() != 1 || d.Val("a") != "12345678901" {
+		t.Error("failed to compact")
+	}
+
+	if stats := d.CompactionStats(); stats.Discarded != 1 || stats.DiscardedBytes != 12 {
+		t.Error("invalid stats", stats)
+	}
+}
+
+func TestCompactShadowedRatio(t *testing.T) {
+	d := &Document{}
+	d.SetCompaction(CompactionPolicy{MaxShadowedRatio: 0.4})
+	d.Append("a", "1")
+	d.Append("b", "2")
+	d.Append("a", "3")
+	if d.Len() != 3 {
+		t.Error("compacted too early")
+	}
+
+	d.Append("a", "4")
+	if d.Len() != 2 || d.Val("a") != "4" || d.Val("b") != "2" {
+		t.Error("failed to compact")
+	}
+}
+
+func TestCompactInTransaction(t *testing.T) {
+	d := &Document{}
+	d.SetCompaction(CompactionPolicy{MaxEntries: 1})
+	d.Begin()
+	d.Append("a", "1")
+	d.Append("a", "2")
+	if d.Len() != 2 {
+		t.Error("compacted in transaction")
+	}
+
+	d.Commit()
+	if d.Len() != 1 {
+		t.Error("failed to compact on commit")
+	}
+}
+
+func TestCompactionHistory(t *testing.T) {
+	d := &Document{}
+	d.EnableHistory(0)
+	d.SetCompaction(CompactionPolicy{MaxEntries: 2})
+	d.Append("a", "1")
+	d.Append("a", "2")
+	d.Append("a", "3")
+	checkVals(t, d, "3")
+
+	if !d.Undo() {
+		t.Error("failed to undo")
+		return
+	}
+
+	checkVals(t, d, "1", "2")
+
+	if !d.Redo() {
+		t.Error("failed to redo")
+		return
+	}
+
+	checkVals(t, d, "3")
+}
+
+func TestCompactionKeyParts(t *testing.T) {
+	d := &Document{}
+	d.SetCompaction(CompactionPolicy{MaxEntries: 1})
+	d.AppendVal([]string{"a", "b"}, "1")
+	d.AppendVal([]string{"a.b"}, "2")
+	if d.Len() != 2 || d.ValOf("a", "b") != "1" || d.ValOf("a.b") != "2" {
+		t.Error("invalid compaction", d.Entries())
+	}
+}
@@ -15,16 +15,17 @@ Based on the INI file format, but (tries to be) well defined and support hierarc
 
 
 Streaming only possible where the underlying network protocol makes sure that the packets all arrive, are intact
-and the order of receiving is the same of sending.
+and the order of receiving is the same of sending. Server and Client stream entries over TCP or Unix
+sockets.
 
 
-The functions in the package are not synchronized.
+The functions in the package are not synchronized. For concurrent access, use SyncDocument.
 
 
 The possible evaluation methods of the same keys.
 
 
-CAST: Continuously Applied State Transfer
+CAST: Continuously Applied State Transfer, implemented by StateApplier.
 "Consistency is the last refuge of the unimaginative." Oscar Wilde
 
 
@@ -117,5 +118,14 @@ Comment
 - A comment closed by EOF gives an entry without a key and a value.
 - Inside a comment, '\', '\n' can be escaped. At the comment boundaries, ' ' and '\t' can be escaped, causing
   the escaped character be part of the section declaration.
+
+
+Deletion
+
+- A value consisting of only an escaped '-', '\-', marks the entry as deleted, a tombstone. It tells that the
+  key doesn't exist anymore.
+- A '-' in any other position, or a value of a single, unescaped '-', is part of the value.
+- A deleted entry has an empty value.
+- The effective value of a key with a deleted last entry is undefined, and the key is considered missing.
 */
 package keyval
@@ -13,7 +13,22 @@ const (
 	ListDepthFirst
 )
 
-type Document struct{ entries []*Entry }
+type Document struct {
+	entries    []*Entry
+	tx         [][]op
+	history    *history
+	subs       []*subscription
+	snapshot   *Snapshot
+	compaction *compaction
+	positions  map[*Entry]Position
+	defaulted  map[*Entry]bool
+}
+
+// Position tells where an entry was read from. File is empty when the entry was not loaded from a file.
+type Position struct {
+	File string
+	Line int
+}
 
 type CompareFunc func(*Entry, *Entry) bool
 
@@ -109,9 +124,63 @@ func (d *Document) Entries() []*Entry {
 	return d.entries
 }
 
+func (d *Document) replaceEntry(at, n int, e []*Entry) {
+	var removed []*Entry
+	if len(d.subs) > 0 {
+		removed = make([]*Entry, n)
+		copy(removed, d.entries[at:at+n])
+	}
+
+	if d.compaction != nil {
+		for _, ei := range d.entries[at : at+n] {
+			d.compaction.remove(ei)
+		}
+
+		for _, ei := range e {
+			d.compaction.add(ei)
+		}
+	}
+
+	d.forget(d.entries[at:at+n], e)
+	if at+n == len(d.entries) {
+		d.entries = append(d.entries[:at], e...)
+	} else {
+		tail := make([]*Entry, 0, len(e)+len(d.entries)-at-n)
+		tail = append(append(tail, e...), d.entries[at+n:]...)
+		d.entries = append(d.entries[:at], tail...)
+	}
+
+	d.notifyReplace(at, removed, e)
+}
+
 func (d *Document) ReplaceEntry(at, n int, e ...*Entry) {
 	at, n = d.truncRange(at, n)
-	d.entries = append(d.entries[:at], append(e, d.entries[at+n:]...)...)
+	if !d.recording() {
+		d.replaceEntry(at, n, e)
+		d.autoCompact()
+		return
+	}
+
+	removed := make([]*Entry, n)
+	copy(removed, d.entries[at:at+n])
+	inserted := make([]*Entry, len(e))
+	copy(inserted, e)
+
+	var insertedMeta []entryMeta
+	removedMeta := d.metaOf(removed)
+	d.replaceEntry(at, n, inserted)
+	d.record(op{
+		undo: func() {
+			insertedMeta = d.metaOf(inserted)
+			d.replaceEntry(at, len(inserted), removed)
+			d.restoreMeta(removed, removedMeta)
+		},
+		redo: func() {
+			removedMeta = d.metaOf(removed)
+			d.replaceEntry(at, len(removed), inserted)
+			d.restoreMeta(inserted, insertedMeta)
+		}})
+	d.autoCompact()
 }
 
 func (d *Document) InsertEntry(at int, e ...*Entry) {
@@ -122,21 +191,54 @@ func (d *Document) AppendEntry(e ...*Entry) {
 	d.InsertEntry(d.Len(), e...)
 }
 
-// this makes not much sense this way
-func (d *Document) DeleteAt(at, n int, key ...string) {
+// deleteWhere deletes the entries matching f in the range, merging the consecutive matches into a single
+// replace
+func (d *Document) deleteWhere(at, n int, f func(*Entry) bool) []*Entry {
+	d.Begin()
+	defer d.Commit()
+
+	var removed []*Entry
 	at, n = d.truncRange(at, n)
-	for i := 0; i < n; {
-		e := d.EntryAt(i)
-		if e != nil && KeyEq(e.Key, key) {
-			d.ReplaceEntry(i, 1)
-			n--
-		} else {
+	for i := at; i < at+n; {
+		run := 0
+		for i+run < at+n && f(d.EntryAt(i+run)) {
+			run++
+		}
+
+		if run == 0 {
 			i++
+			continue
 		}
+
+		removed = append(removed, d.Entries()[i:i+run]...)
+		d.ReplaceEntry(i, run)
+		n -= run
 	}
+
+	return removed
+}
+
+func (d *Document) DeleteRange(at, n int) []*Entry {
+	return d.deleteWhere(at, n, func(*Entry) bool { return true })
+}
+
+func (d *Document) DeleteFunc(f func(*Entry) bool) []*Entry {
+	return d.deleteWhere(0, d.Len(), func(e *Entry) bool { return e != nil && f(e) })
+}
+
+// DeletePrefix deletes the entries with the key and the entries below it.
+func (d *Document) DeletePrefix(key ...string) []*Entry {
+	return d.DeleteFunc(func(e *Entry) bool { return hasPrefix(e.Key, key) })
+}
+
+func (d *Document) DeleteAt(at, n int, key ...string) []*Entry {
+	return d.deleteWhere(at, n, func(e *Entry) bool { return e != nil && KeyEq(e.Key, key) })
 }
 
 func (d *Document) DeleteEntry(e ...*Entry) {
+	d.Begin()
+	defer d.Commit()
+
 	for i := 0; len(e) > 0 && i < d.Len(); {
 		if d.EntryAt(i) == e[0] {
 			d.ReplaceEntry(i, 1)
@@ -147,8 +249,21 @@ func (d *Document) DeleteEntry(e ...*Entry) {
 	}
 }
 
-func (d *Document) ValOf(key ...string) string {
+// effectiveEntry returns the last entry of the key, or nil, when the key is missing, or its last entry is
+// deleted
+func (d *Document) effectiveEntry(key ...string) *Entry {
 	_, e := d.EntryOf(key...)
+	if e == nil || e.Deleted {
+		return nil
+	}
+
+	return e
+}
+
+// ValOf returns the effective value of the key. It returns an empty string when the key is missing, or when its
+// last entry is a tombstone.
+func (d *Document) ValOf(key ...string) string {
+	e := d.effectiveEntry(key...)
 	if e == nil {
 		return ""
 	}
@@ -156,35 +271,114 @@ func (d *Document) ValOf(key ...string) string {
 	return e.Val
 }
 
+// ValsOf returns the values of the entries with the key, following the last tombstone of the key.
 func (d *Document) ValsOf(key ...string) []string {
 	var vals []string
 	for _, e := range d.EntriesOf(key...) {
-		if e != nil {
-			vals = append(vals, e.Val)
+		if e.Deleted {
+			vals = nil
+			continue
 		}
+
+		vals = append(vals, e.Val)
 	}
 
 	return vals
 }
 
+func (d *Document) setString(e *Entry, field *string, val string) {
+	old := *field
+	*field = val
+	if d.compaction != nil {
+		d.compaction.size += len(val) - len(old)
+	}
+
+	t := ChangeVal
+	if field == &e.Comment {
+		t = ChangeComment
+	}
+
+	if old != val {
+		d.notify(Change{Type: t, Key: e.Key, Old: old, New: val, Index: -1, Entry: e})
+	}
+}
+
+func (d *Document) setField(e *Entry, field *string, val string) {
+	old := *field
+	d.setString(e, field, val)
+	if !d.recording() {
+		return
+	}
+
+	d.record(op{
+		undo: func() { d.setString(e, field, old) },
+		redo: func() { d.setString(e, field, val) }})
+}
+
 func (d *Document) SetValOf(key []string, val string) {
+	d.Begin()
+	defer d.Commit()
+
 	for _, e := range d.EntriesOf(key...) {
 		if e != nil {
-			e.Val = val
+			d.setField(e, &e.Val, val)
 		}
 	}
 }
 
+// override sets the value of the last entry of the key, and deletes the rest, or appends a new entry if the
+// key doesn't exist.
+func (d *Document) override(key []string, val string) {
+	d.Begin()
+	defer d.Commit()
+
+	entries := d.EntriesOf(key...)
+	if len(entries) == 0 || entries[len(entries)-1].Deleted {
+		d.DeleteEntry(entries...)
+		d.AppendEntry(&Entry{Key: key, Val: val})
+		return
+	}
+
+	last := entries[len(entries)-1]
+	d.DeleteEntry(entries[:len(entries)-1]...)
+	d.setField(last, &last.Val, val)
+}
+
+// InsertVal inserts a new entry. When the entries before and after the insert position belong to the same
+// comment block, the new entry inherits their comment, the same way as an entry inserted into a commented
+// block of a keyval document would.
 func (d *Document) InsertVal(at int, key []string, val string) {
-	d.InsertEntry(at, &Entry{Key: key, Val: val})
+	at, _ = d.truncRange(at, 0)
+	var before, after *Entry
+	for i := at - 1; i >= 0 && before == nil; i-- {
+		before = d.EntryAt(i)
+	}
+
+	for i := at; i < d.Len() && after == nil; i++ {
+		after = d.EntryAt(i)
+	}
+
+	var comment string
+	if before != nil && after != nil && before.Comment == after.Comment {
+		comment = before.Comment
+	}
+
+	d.InsertEntry(at, &Entry{Key: key, Val: val, Comment: comment})
 }
 
 func (d *Document) AppendVal(key []string, val string) {
 	d.InsertVal(d.Len(), key, val)
 }
 
-func (d *Document) DeleteOf(key ...string) {
-	d.DeleteAt(0, d.Len(), key...)
+// AppendDeletedOf appends a deleted entry, a tombstone, marking that the key doesn't exist anymore.
+func (d *Document) AppendDeletedOf(key ...string) {
+	k := make([]string, len(key))
+	copy(k, key)
+	d.AppendEntry(&Entry{Key: k, Deleted: true})
+}
+
+func (d *Document) DeleteOf(key ...string) []*Entry {
+	return d.DeleteAt(0, d.Len(), key...)
 }
 
 func (d *Document) CommentOf(key ...string) string {
@@ -197,9 +391,12 @@ func (d *Document) CommentOf(key ...string) string {
 }
 
 func (d *Document) SetCommentOf(key []string, comment string) {
+	d.Begin()
+	defer d.Commit()
+
 	entries := d.EntriesOf(key...)
 	for _, e := range entries {
-		e.Comment = comment
+		d.setField(e, &e.Comment, comment)
 	}
 }
 
@@ -223,8 +420,12 @@ func (d *Document) Append(key string, val string) {
 	d.Insert(d.Len(), key, val)
 }
 
-func (d *Document) Delete(key string) {
-	d.DeleteOf(SplitKey(key)...)
+func (d *Document) AppendDeleted(key string) {
+	d.AppendDeletedOf(SplitKey(key)...)
+}
+
+func (d *Document) Delete(key string) []*Entry {
+	return d.DeleteOf(SplitKey(key)...)
 }
 
 func (d *Document) Comment(key string) string {
@@ -263,7 +464,45 @@ func (d *Document) Copy() *Document {
 	return c
 }
 
+func copyEntry(e *Entry) *Entry {
+	if e == nil {
+		return nil
+	}
+
+	c := *e
+	c.Key = make([]string, len(e.Key))
+	copy(c.Key, e.Key)
+	return &c
+}
+
+// DeepCopy copies the entries, too, so modifying the entries of the copy doesn't affect the original.
+func (d *Document) DeepCopy() *Document {
+	c := &Document{}
+	c.entries = make([]*Entry, len(d.entries))
+	for i, e := range d.entries {
+		c.entries[i] = copyEntry(e)
+	}
+
+	return c
+}
+
+// TruncateEffective keeps only the last entry of every key, and drops the keys whose last entry is deleted,
+// together with the deleted entry.
 func (d *Document) TruncateEffective() {
+	d.truncateEffective(false)
+}
+
+// TruncateEffectiveTree is like TruncateEffective, but a deleted entry also drops the entries below its key,
+// that precede it.
+func (d *Document) TruncateEffectiveTree() {
+	d.truncateEffective(true)
+}
+
+func (d *Document) truncateEffective(tree bool) {
+	d.Begin()
+	defer d.Commit()
+
+	var deleted [][]string
 	found := make(map[string]bool)
 	for i := d.Len() - 1; i >= 0; i-- {
 		e := d.EntryAt(i)
@@ -272,11 +511,28 @@ func (d *Document) TruncateEffective() {
 			continue
 		}
 
-		ks := JoinKey(e.Key)
-		if found[ks] {
+		ck := canonicalKey(e.Key)
+		if found[ck] {
 			d.ReplaceEntry(i, 1)
-		} else {
-			found[ks] = true
+			continue
+		}
+
+		found[ck] = true
+		if e.Deleted {
+			deleted = append(deleted, e.Key)
+			d.ReplaceEntry(i, 1)
+			continue
+		}
+
+		if !tree {
+			continue
+		}
+
+		for _, dk := range deleted {
+			if hasPrefix(e.Key, dk) {
+				d.ReplaceEntry(i, 1)
+				break
+			}
 		}
 	}
 }
@@ -325,6 +581,7 @@ func (d *Document) ReadAllEntries(r *EntryReader) error {
 		}
 
 		d.AppendEntry(entry)
+		d.setPosition(entry, Position{Line: r.Line()})
 
 		if err != nil {
 			return err
@@ -332,6 +589,89 @@ func (d *Document) ReadAllEntries(r *EntryReader) error {
 	}
 }
 
+func (d *Document) setPosition(e *Entry, p Position) {
+	if d.positions == nil {
+		d.positions = make(map[*Entry]Position)
+	}
+
+	d.positions[e] = p
+}
+
+// entryMeta holds what the document knows about an entry outside of the entry itself. It is dropped together
+// with the entry, and restored when undo or redo restores the entry.
+type entryMeta struct {
+	position    Position
+	hasPosition bool
+	defaulted   bool
+}
+
+func (d *Document) metaOf(entries []*Entry) []entryMeta {
+	if len(d.positions) == 0 && len(d.defaulted) == 0 {
+		return nil
+	}
+
+	meta := make([]entryMeta, len(entries))
+	for i, e := range entries {
+		meta[i].position, meta[i].hasPosition = d.positions[e]
+		meta[i].defaulted = d.defaulted[e]
+	}
+
+	return meta
+}
+
+func (d *Document) restoreMeta(entries []*Entry, meta []entryMeta) {
+	for i, m := range meta {
+		if m.hasPosition {
+			d.setPosition(entries[i], m.position)
+		}
+
+		if m.defaulted {
+			d.setDefaulted(entries[i])
+		}
+	}
+}
+
+// forget drops the data about the removed entries, unless they are inserted again
+func (d *Document) forget(removed, inserted []*Entry) {
+	if len(d.positions) == 0 && len(d.defaulted) == 0 {
+		return
+	}
+
+	var keep map[*Entry]bool
+	if len(inserted) > 0 {
+		keep = make(map[*Entry]bool)
+		for _, e := range inserted {
+			keep[e] = true
+		}
+	}
+
+	for _, e := range removed {
+		if !keep[e] {
+			delete(d.positions, e)
+			delete(d.defaulted, e)
+		}
+	}
+}
+
+func (d *Document) setDefaulted(e *Entry) {
+	if d.defaulted == nil {
+		d.defaulted = make(map[*Entry]bool)
+	}
+
+	d.defaulted[e] = true
+}
+
+// PositionOf returns the position of an entry read from text, or the zero value, when the position is not
+// known.
+func (d *Document) PositionOf(e *Entry) Position {
+	return d.positions[e]
+}
+
+// LineOf returns the line number of an entry read from text, or 0, when the line is not known.
+func (d *Document) LineOf(e *Entry) int {
+	return d.PositionOf(e).Line
+}
+
 func (d *Document) ReadAll(r io.Reader) error {
 	return d.ReadAllEntries(NewEntryReader(r))
 }
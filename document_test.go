@@ -193,3 +193,174 @@ func TestGetEntries(t *testing.T) {
 // 		t.Error("failed to append entry")
 // 	}
 // }
+
+const deleteTestDoc = `a = 1
+b = 2
+a = 3
+a/b = 4
+a = 5
+c = 6
+`
+
+func checkRemoved(t *testing.T, removed []*Entry, vals ...string) {
+	if len(removed) != len(vals) {
+		t.Error("invalid number of removed entries", len(removed), len(vals))
+		return
+	}
+
+	for i, v := range vals {
+		if removed[i].Val != v {
+			t.Error(i, "invalid removed entry", removed[i].Val, v)
+		}
+	}
+}
+
+func TestDeleteAt(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(deleteTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	checkRemoved(t, d.DeleteAt(2, 3, "a"), "3", "5")
+	checkVals(t, d, "1", "2", "4", "6")
+}
+
+func TestDeleteOf(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(deleteTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	checkRemoved(t, d.Delete("a"), "1", "3", "5")
+	checkVals(t, d, "2", "4", "6")
+}
+
+func TestDeleteRange(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(deleteTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	checkRemoved(t, d.DeleteRange(1, 2), "2", "3")
+	checkVals(t, d, "1", "4", "5", "6")
+	checkRemoved(t, d.DeleteRange(3, 9), "6")
+	checkRemoved(t, d.DeleteRange(9, 1))
+}
+
+func TestDeleteFunc(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(deleteTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	d.AppendEntry(nil)
+	removed := d.DeleteFunc(func(e *Entry) bool { return e.Val > "3" })
+	checkRemoved(t, removed, "4", "5", "6")
+	if d.Len() != 4 || d.EntryAt(3) != nil {
+		t.Error("failed to keep entries")
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(deleteTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	checkRemoved(t, d.DeletePrefix("a"), "1", "3", "4", "5")
+	checkVals(t, d, "2", "6")
+}
+
+func TestDeleteUndo(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(deleteTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	d.EnableHistory(0)
+	d.DeletePrefix("a")
+	d.Undo()
+	checkVals(t, d, "1", "2", "3", "4", "5", "6")
+}
+
+func TestDeleted(t *testing.T) {
+	d := &Document{}
+	d.Append("a.b", "1")
+	d.Append("a", "2")
+	d.Append("c", "-")
+	d.AppendDeleted("a")
+	d.Append("a.c", "3")
+
+	if _, ok := d.Lookup("a"); ok || d.Val("a") != "" || len(d.Vals("a")) != 0 {
+		t.Error("failed to delete key")
+	}
+
+	if d.Val("c") != "-" {
+		t.Error("invalid value")
+	}
+
+	d.Append("a", "4")
+	if vals := d.Vals("a"); len(vals) != 1 || vals[0] != "4" {
+		t.Error("invalid values", vals)
+	}
+
+	if d.Snapshot().Val("a") != "4" {
+		t.Error("invalid snapshot value")
+	}
+
+	var b bytes.Buffer
+	if err := d.WriteAll(&b); err != nil {
+		t.Error(err)
+		return
+	}
+
+	rd := &Document{}
+	if err := rd.ReadAll(&b); err != nil && err != io.EOF {
+		t.Error(err)
+		return
+	}
+
+	if !rd.Equal(d) {
+		t.Error("failed to read written deletion", b.String())
+	}
+}
+
+func TestTruncateEffectiveDeleted(t *testing.T) {
+	d := &Document{}
+	d.Append("a.b", "1")
+	d.Append("a", "2")
+	d.Append("c", "3")
+	d.AppendDeleted("a")
+	d.Append("a.c", "4")
+
+	c := d.DeepCopy()
+	d.TruncateEffective()
+	checkKeys(t, d, "a.b", "c", "a.c")
+
+	c.TruncateEffectiveTree()
+	checkKeys(t, c, "c", "a.c")
+}
+
+func TestPositionsDropped(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString("a = 1\na = 2\nb = 3\n")); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+
+	d.TruncateEffective()
+	if len(d.positions) != 2 {
+		t.Error("failed to drop positions", len(d.positions))
+	}
+
+	d.EnableHistory(0)
+	b := d.EntryAt(1)
+	d.Delete("b")
+	if len(d.positions) != 1 {
+		t.Error("failed to drop position")
+	}
+
+	d.Undo()
+	if d.LineOf(b) != 3 {
+		t.Error("failed to restore position")
+	}
+
+	d.Redo()
+	d.Undo()
+	if d.LineOf(b) != 3 {
+		t.Error("failed to restore position")
+	}
+}
@@ -0,0 +1,121 @@
+package keyval
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const DefaultEnvSeparator = "__"
+
+// EnvMapping maps environment variable names to keys, e.g. with the prefix APP_, APP_SERVER__PORT to
+// server.port. The names without the prefix are ignored. Unless PreserveCase is set, the names are lowercased
+// when mapped to keys, and the keys are uppercased when mapped to names.
+type EnvMapping struct {
+	Prefix string
+
+	// Separator, when set, is used instead of DefaultEnvSeparator.
+	Separator    string
+	PreserveCase bool
+}
+
+func (m *EnvMapping) separator() string {
+	if m.Separator != "" {
+		return m.Separator
+	}
+
+	return DefaultEnvSeparator
+}
+
+func (m *EnvMapping) Key(name string) ([]string, bool) {
+	if !strings.HasPrefix(name, m.Prefix) || len(name) == len(m.Prefix) {
+		return nil, false
+	}
+
+	name = name[len(m.Prefix):]
+	if !m.PreserveCase {
+		name = strings.ToLower(name)
+	}
+
+	return strings.Split(name, m.separator()), true
+}
+
+func envChar(c rune) rune {
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' {
+		return c
+	}
+
+	return '_'
+}
+
+// Name returns the environment variable name of a key. Characters not allowed in the names are replaced with
+// '_'.
+func (m *EnvMapping) Name(key []string) string {
+	name := strings.Map(envChar, strings.Join(key, m.separator()))
+	if !m.PreserveCase {
+		name = strings.ToUpper(name)
+	}
+
+	return m.Prefix + name
+}
+
+// Document returns the mapped entries from environ, in the form returned by os.Environ.
+func (m *EnvMapping) Document(environ []string) *Document {
+	d := &Document{}
+	for _, ev := range environ {
+		i := strings.Index(ev, "=")
+		if i <= 0 {
+			continue
+		}
+
+		if key, ok := m.Key(ev[:i]); ok {
+			d.AppendEntry(&Entry{Key: key, Val: ev[i+1:]})
+		}
+	}
+
+	return d
+}
+
+// OverlayEnv sets the values of the mapped environment variables from environ, in the form returned by
+// os.Environ. When a key has multiple entries, only the last one is kept, otherwise new keys are appended.
+func (d *Document) OverlayEnv(m *EnvMapping, environ []string) {
+	d.Begin()
+	defer d.Commit()
+
+	for _, e := range m.Document(environ).Entries() {
+		d.override(e.Key, e.Val)
+	}
+}
+
+func envUnsafe(c rune) bool {
+	return envChar(c) != c && !strings.ContainsRune("-./:,@+", c)
+}
+
+func quoteEnv(val string) string {
+	if val != "" && strings.IndexFunc(val, envUnsafe) < 0 {
+		return val
+	}
+
+	return strconv.Quote(val)
+}
+
+// WriteEnv writes the effective values in .env format, one NAME=value line for each key, quoting the values
+// that contain whitespace or special characters.
+func (d *Document) WriteEnv(w io.Writer, m *EnvMapping) error {
+	effective := d.DeepCopy()
+	effective.TruncateEffective()
+
+	bw := bufio.NewWriter(w)
+	for _, e := range effective.Entries() {
+		if len(e.Key) == 0 {
+			continue
+		}
+
+		if _, err := bw.WriteString(m.Name(e.Key) + "=" + quoteEnv(e.Val) + "\n"); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,73 @@
+package keyval
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEnvMapping(t *testing.T) {
+	m := &EnvMapping{Prefix: "APP_"}
+	if key, ok := m.Key("APP_SERVER__PORT"); !ok || JoinKey(key) != "server.port" {
+		t.Error("failed to map name", key)
+	}
+
+	if _, ok := m.Key("PATH"); ok {
+		t.Error("failed to ignore name")
+	}
+
+	if name := m.Name([]string{"server", "http-port"}); name != "APP_SERVER__HTTP_PORT" {
+		t.Error("failed to map key", name)
+	}
+
+	m = &EnvMapping{Separator: "_", PreserveCase: true}
+	if key, ok := m.Key("Server_Port"); !ok || JoinKey(key) != "Server.Port" {
+		t.Error("failed to map name", key)
+	}
+}
+
+func TestOverlayEnv(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8080")
+	d.Append("server.host", "a.example.org")
+	d.Append("server.host", "b.example.org")
+
+	d.OverlayEnv(&EnvMapping{Prefix: "APP_"}, []string{
+		"PATH=/bin",
+		"APP_SERVER__PORT=9000",
+		"APP_SERVER__HOST=c.example.org",
+		"APP_CLIENT__TIMEOUT=3s",
+		"APP_=ignored"})
+
+	checkKeys(t, d, "server.port", "server.host", "client.timeout")
+	checkVals(t, d, "9000", "c.example.org", "3s")
+}
+
+func TestWriteEnv(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8080")
+	d.Append("server.name", "test server")
+	d.Append("server.port", "9000")
+	d.Append("server.url", "http://example.org/")
+
+	buf := bytes.NewBuffer(nil)
+	if err := d.WriteEnv(buf, &EnvMapping{Prefix: "APP_"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	expect := "APP_SERVER__NAME=\"test server\"\nAPP_SERVER__PORT=9000\nAPP_SERVER__URL=http://example.org/\n"
+	if buf.String() != expect {
+		t.Error("failed to write env")
+		t.Log(buf.String())
+	}
+}
+
+func TestOverlayEnvDeleted(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8080")
+	d.AppendDeleted("server.port")
+	d.OverlayEnv(&EnvMapping{Prefix: "APP_"}, []string{"APP_SERVER__PORT=9000"})
+	if v, ok := d.Lookup("server.port"); !ok || v != "9000" || d.Len() != 1 {
+		t.Error("failed to overlay deleted key", d.Entries())
+	}
+}
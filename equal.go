@@ -0,0 +1,145 @@
+package keyval
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"sort"
+)
+
+func (d *Document) Equal(o *Document) bool {
+	if d.Len() != o.Len() {
+		return false
+	}
+
+	for i, e := range d.Entries() {
+		if !entryEq(e, o.EntryAt(i)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// the key parts are length prefixed, so that keys with parts containing '.' don't collide
+func canonicalKey(key []string) string {
+	b := binary.AppendUvarint(nil, uint64(len(key)))
+	for _, k := range key {
+		b = binary.AppendUvarint(b, uint64(len(k)))
+		b = append(b, k...)
+	}
+
+	return string(b)
+}
+
+func (d *Document) effectiveVals() map[string]string {
+	vals := make(map[string]string)
+	for _, e := range d.Entries() {
+		switch {
+		case e == nil:
+		case e.Deleted:
+			delete(vals, canonicalKey(e.Key))
+		default:
+			vals[canonicalKey(e.Key)] = e.Val
+		}
+	}
+
+	return vals
+}
+
+// EquivalentEffective tells whether the two documents have the same effective values for the same keys,
+// ignoring the order, the comments and the shadowed entries.
+func (d *Document) EquivalentEffective(o *Document) bool {
+	dv, ov := d.effectiveVals(), o.effectiveVals()
+	if len(dv) != len(ov) {
+		return false
+	}
+
+	for k, v := range dv {
+		if vo, ok := ov[k]; !ok || vo != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hashString(h hash.Hash, s string) {
+	h.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	h.Write([]byte(s))
+}
+
+// Hash returns the SHA-256 hash of the entries, including their order and comments. Equal documents have the
+// same hash.
+func (d *Document) Hash() []byte {
+	h := sha256.New()
+	for _, e := range d.Entries() {
+		if e == nil {
+			h.Write([]byte{0})
+			continue
+		}
+
+		if e.Deleted {
+			h.Write([]byte{2})
+		} else {
+			h.Write([]byte{1})
+		}
+
+		hashString(h, canonicalKey(e.Key))
+		hashString(h, e.Val)
+		hashString(h, e.Comment)
+	}
+
+	return h.Sum(nil)
+}
+
+// EffectiveHash returns the SHA-256 hash of the effective values, ordered by key. Documents that are
+// equivalent by EquivalentEffective have the same effective hash.
+func (d *Document) EffectiveHash() []byte {
+	vals := d.effectiveVals()
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		hashString(h, k)
+		hashString(h, vals[k])
+	}
+
+	return h.Sum(nil)
+}
+
+// ChangedKeys returns the keys whose effective value differs in o, including the keys missing from only one of
+// the documents. Deleted keys count as missing. The keys are ordered by their first appearance in o, followed by the keys missing from o.
+func (d *Document) ChangedKeys(o *Document) [][]string {
+	dv, ov := d.effectiveVals(), o.effectiveVals()
+	var changed [][]string
+	seen := make(map[string]bool)
+	collect := func(doc *Document) {
+		for _, e := range doc.Entries() {
+			if e == nil {
+				continue
+			}
+
+			ck := canonicalKey(e.Key)
+			if seen[ck] {
+				continue
+			}
+
+			seen[ck] = true
+			v, dok := dv[ck]
+			w, ook := ov[ck]
+			if dok != ook || v != w {
+				changed = append(changed, e.Key)
+			}
+		}
+	}
+
+	collect(o)
+	collect(d)
+	return changed
+}
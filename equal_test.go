@@ -0,0 +1,79 @@
+package keyval
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	d1 := &Document{}
+	d1.Append("a", "1")
+	d1.Append("b", "2")
+
+	d2 := d1.DeepCopy()
+	if !d1.Equal(d2) || !bytes.Equal(d1.Hash(), d2.Hash()) {
+		t.Error("failed to compare equal documents")
+	}
+
+	d2.SetComment("b", "comment")
+	if d1.Equal(d2) || bytes.Equal(d1.Hash(), d2.Hash()) {
+		t.Error("failed to compare different comments")
+	}
+
+	d3 := &Document{}
+	d3.Append("b", "2")
+	d3.Append("a", "1")
+	if d1.Equal(d3) || bytes.Equal(d1.Hash(), d3.Hash()) {
+		t.Error("failed to compare different order")
+	}
+}
+
+func TestEquivalentEffective(t *testing.T) {
+	d1 := &Document{}
+	d1.Append("a", "1")
+	d1.Append("b", "2")
+
+	d2 := &Document{}
+	d2.Append("b", "3")
+	d2.Append("b", "2")
+	d2.Append("a", "1")
+	d2.SetComment("a", "comment")
+
+	if !d1.EquivalentEffective(d2) || !bytes.Equal(d1.EffectiveHash(), d2.EffectiveHash()) {
+		t.Error("failed to compare equivalent documents")
+	}
+
+	d2.Append("c", "4")
+	if d1.EquivalentEffective(d2) || bytes.Equal(d1.EffectiveHash(), d2.EffectiveHash()) {
+		t.Error("failed to compare different documents")
+	}
+
+	d3 := &Document{}
+	d3.AppendVal([]string{"a.b"}, "1")
+	d4 := &Document{}
+	d4.AppendVal([]string{"a", "b"}, "1")
+	if d3.EquivalentEffective(d4) || bytes.Equal(d3.EffectiveHash(), d4.EffectiveHash()) {
+		t.Error("failed to compare keys containing '.'")
+	}
+}
+
+func TestChangedKeys(t *testing.T) {
+	d1 := &Document{}
+	d1.Append("a", "1")
+	d1.Append("b", "2")
+
+	d2 := &Document{}
+	d2.Append("a", "1")
+	d2.AppendDeleted("x")
+	if changed := d1.ChangedKeys(d2); len(changed) != 1 || JoinKey(changed[0]) != "b" {
+		t.Error("invalid changed keys", changed)
+	}
+
+	d3 := &Document{}
+	d3.Append("x", "")
+	d4 := &Document{}
+	d4.AppendDeleted("x")
+	if changed := d3.ChangedKeys(d4); len(changed) != 1 || JoinKey(changed[0]) != "x" {
+		t.Error("invalid changed keys", changed)
+	}
+}
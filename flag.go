@@ -0,0 +1,106 @@
+package keyval
+
+import (
+	"flag"
+	"strings"
+)
+
+type keyFlag struct {
+	doc     *Document
+	key     []string
+	boolean bool
+}
+
+func (f *keyFlag) String() string {
+	if f == nil || f.doc == nil {
+		return ""
+	}
+
+	return f.doc.ValOf(f.key...)
+}
+
+func (f *keyFlag) Set(val string) error {
+	f.doc.override(f.key, val)
+	return nil
+}
+
+func (f *keyFlag) IsBoolFlag() bool {
+	return f.boolean
+}
+
+// BindFlagOf defines a flag, that, when set, overrides the value of the key.
+func (d *Document) BindFlagOf(fs *flag.FlagSet, name string, key []string, usage string) {
+	fs.Var(&keyFlag{doc: d, key: key}, name, usage)
+}
+
+func (d *Document) BindFlag(fs *flag.FlagSet, key string, usage string) {
+	d.BindFlagOf(fs, key, SplitKey(key), usage)
+}
+
+// DefineFlags defines a flag for every key in the document, named by the joined key, with the comment of the
+// effective entry as the usage text. The flags already defined in the flag set are skipped.
+func (d *Document) DefineFlags(fs *flag.FlagSet) {
+	seen := make(map[string]bool)
+	for _, e := range d.Entries() {
+		if e == nil || len(e.Key) == 0 {
+			continue
+		}
+
+		name := JoinKey(e.Key)
+		if seen[name] || fs.Lookup(name) != nil {
+			continue
+		}
+
+		seen[name] = true
+		d.BindFlagOf(fs, name, e.Key, d.CommentOf(e.Key...))
+	}
+}
+
+// ApplyFlags overrides the values of the keys named by the flags that were set in the parsed flag set. It
+// can be used with the flags defined independently from the document. When names are passed, only the flags
+// with those names are applied, otherwise only the flags naming the keys already present in the document.
+func (d *Document) ApplyFlags(fs *flag.FlagSet, names ...string) {
+	allowed := make(map[string]bool)
+	for _, n := range names {
+		allowed[n] = true
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if _, ok := f.Value.(*keyFlag); ok {
+			return
+		}
+
+		key := SplitKey(f.Name)
+		if len(names) > 0 && !allowed[f.Name] {
+			return
+		}
+
+		if len(names) == 0 && len(d.EntriesOf(key...)) == 0 {
+			return
+		}
+
+		d.override(key, f.Value.String())
+	})
+}
+
+// DefineFlags defines a flag for every key of the schema without wildcards, setting the values in the
+// document. The usage text shows the type and the default value of the key.
+func (s *Schema) DefineFlags(fs *flag.FlagSet, d *Document) {
+	for _, r := range s.rules {
+		name := JoinKey(r.key)
+		if !r.literal() || fs.Lookup(name) != nil {
+			continue
+		}
+
+		usage := []string{r.typ}
+		if r.required {
+			usage = append(usage, "required")
+		}
+
+		if r.def != nil {
+			usage = append(usage, "default: "+*r.def)
+		}
+
+		fs.Var(&keyFlag{doc: d, key: r.key, boolean: r.typ == "bool"}, name, strings.Join(usage, ", "))
+	}
+}
@@ -0,0 +1,114 @@
+package keyval
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestDefineFlags(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8080")
+	d.SetComment("server.port", "listen port")
+	d.Append("server.host", "a.example.org")
+	d.Append("server.host", "b.example.org")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	d.DefineFlags(fs)
+	if f := fs.Lookup("server.port"); f == nil || f.Usage != "listen port" || f.DefValue != "8080" {
+		t.Error("failed to define flag")
+	}
+
+	if err := fs.Parse([]string{"-server.port=9000", "-server.host", "c.example.org"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	checkVals(t, d, "9000", "c.example.org")
+}
+
+func TestApplyFlags(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8080")
+	d.OverlayEnv(&EnvMapping{Prefix: "APP_"}, []string{"APP_SERVER__PORT=9000", "APP_SERVER__HOST=example.org"})
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("server.port", 0, "listen port")
+	fs.String("server.host", "", "host name")
+	fs.String("server.name", "", "server name")
+	fs.String("config", "", "config file")
+	if err := fs.Parse([]string{"-server.port=9001", "-config=app.k", "-server.name=app"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	d.ApplyFlags(fs)
+	if d.Val("server.port") != "9001" || d.Val("server.host") != "example.org" {
+		t.Error("failed to apply flags")
+	}
+
+	if _, ok := d.Lookup("config"); ok {
+		t.Error("unexpected key")
+	}
+
+	if _, ok := d.Lookup("server.name"); ok {
+		t.Error("unexpected key")
+	}
+
+	d.ApplyFlags(fs, "server.name")
+	if d.Val("server.name") != "app" || d.Val("server.port") != "9001" {
+		t.Error("failed to apply named flag")
+	}
+}
+
+func TestSchemaDefineFlags(t *testing.T) {
+	s, err := ReadSchema(bytes.NewBufferString(`
+[server/port]
+type = int
+default = 8080
+
+[server/debug]
+type = bool
+
+[servers/*/port]
+type = int`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	d := &Document{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	s.DefineFlags(fs, d)
+	if f := fs.Lookup("server.port"); f == nil || f.Usage != "int, default: 8080" {
+		t.Error("failed to define flag")
+	}
+
+	if err := fs.Parse([]string{"-server.debug", "-server.port", "9000"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if d.Val("server.debug") != "true" || d.Val("server.port") != "9000" {
+		t.Error("failed to set values")
+	}
+}
+
+func TestBindFlagDeleted(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8080")
+	d.AppendDeleted("server.port")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	d.BindFlag(fs, "server.port", "listen port")
+	if err := fs.Parse([]string{"-server.port=9000"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if v, ok := d.Lookup("server.port"); !ok || v != "9000" {
+		t.Error("failed to set deleted key")
+	}
+}
@@ -0,0 +1,130 @@
+package keyval
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+const DefaultIncludeKey = "@include"
+
+var ErrIncludeCycle = errors.New("include cycle")
+
+// Loader reads keyval documents from a file system, and replaces the include directives with the entries of
+// the included files. An include directive is an entry whose last key part is the include key, and whose
+// value is a path or a glob pattern, relative to the including file. The keys of the included entries are
+// prefixed with the preceding parts of the directive's key, e.g. the section that the directive appears in.
+// The files matching a pattern are included in lexical order.
+type Loader struct {
+	FS fs.FS
+
+	// IncludeKey, when set, is used instead of DefaultIncludeKey.
+	IncludeKey string
+}
+
+func (l *Loader) includeKey() string {
+	if l.IncludeKey != "" {
+		return l.IncludeKey
+	}
+
+	return DefaultIncludeKey
+}
+
+func hasGlobMeta(p string) bool {
+	return strings.ContainsAny(p, "*?[\\")
+}
+
+func (l *Loader) includedFiles(from, pattern string, state *loadState) ([]string, error) {
+	p := strings.TrimPrefix(pattern, "/")
+	if !path.IsAbs(pattern) {
+		p = path.Join(path.Dir(from), pattern)
+	}
+
+	if !hasGlobMeta(p) {
+		return []string{p}, nil
+	}
+
+	state.patterns = append(state.patterns, p)
+	return fs.Glob(l.FS, p)
+}
+
+// loadState tracks the files being loaded, to detect include cycles, and collects the loaded files and the
+// include patterns, to watch them for changes
+type loadState struct {
+	loading  map[string]bool
+	files    []string
+	patterns []string
+}
+
+func newLoadState() *loadState {
+	return &loadState{loading: make(map[string]bool)}
+}
+
+func (l *Loader) load(d *Document, name string, prefix []string, state *loadState) error {
+	if state.loading[name] {
+		return fmt.Errorf("%w: %s", ErrIncludeCycle, name)
+	}
+
+	f, err := l.FS.Open(name)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+	state.files = append(state.files, name)
+	state.loading[name] = true
+	defer delete(state.loading, name)
+
+	r := NewEntryReader(f)
+	for {
+		e, err := r.ReadEntry()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("%s:%d: %w", name, r.Line(), err)
+		}
+
+		if e == nil {
+			return nil
+		}
+
+		// entries without a key, e.g. comment-only entries, are kept without the prefix
+		key := e.Key
+		if len(key) > 0 {
+			key = append(append([]string(nil), prefix...), e.Key...)
+		}
+
+		if len(e.Key) > 0 && e.Key[len(e.Key)-1] == l.includeKey() {
+			files, ierr := l.includedFiles(name, e.Val, state)
+			if ierr != nil {
+				return fmt.Errorf("%s:%d: %w", name, r.Line(), ierr)
+			}
+
+			for _, fi := range files {
+				if ierr := l.load(d, fi, key[:len(key)-1], state); ierr != nil {
+					return ierr
+				}
+			}
+		} else {
+			e.Key = key
+			d.AppendEntry(e)
+			d.setPosition(e, Position{File: name, Line: r.Line()})
+		}
+
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// Load reads the named file and the files included by it into a new document. The position of each entry,
+// including the file it came from, is returned by Document.PositionOf.
+func (l *Loader) Load(name string) (*Document, error) {
+	d := &Document{}
+	if err := l.load(d, name, nil, newLoadState()); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
@@ -0,0 +1,91 @@
+package keyval
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoaderInclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/app.k": {Data: []byte(`name = app
+@include = conf.d/*.k
+[server]
+@include = server.k
+port = 9000`)},
+		"etc/server.k":       {Data: []byte("port = 8080\nhost = example.org\n")},
+		"etc/conf.d/b.k":     {Data: []byte("b = 2\n")},
+		"etc/conf.d/a.k":     {Data: []byte("a = 1\n")},
+		"etc/conf.d/ignored": {Data: []byte("c = 3\n")},
+	}
+
+	d, err := (&Loader{FS: fsys}).Load("etc/app.k")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	checkKeys(t, d, "name", "a", "b", "server.port", "server.host", "server.port")
+	if d.Val("server.port") != "9000" {
+		t.Error("failed to keep order")
+	}
+
+	_, host := d.EntryOf("server", "host")
+	if p := d.PositionOf(host); p.File != "etc/server.k" || p.Line != 2 {
+		t.Error("invalid position", p)
+	}
+
+	_, port := d.EntryOf("server", "port")
+	if p := d.PositionOf(port); p.File != "etc/app.k" || p.Line != 5 {
+		t.Error("invalid position", p)
+	}
+}
+
+func TestLoaderIncludeErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.k":       {Data: []byte("@include = b.k\n")},
+		"b.k":       {Data: []byte("@include = a.k\n")},
+		"missing.k": {Data: []byte("@include = none.k\n")},
+		"custom.k":  {Data: []byte("#include = a.k\n!include = c.k\n")},
+		"c.k":       {Data: []byte("c = 3\n")},
+	}
+
+	if _, err := (&Loader{FS: fsys}).Load("a.k"); !errors.Is(err, ErrIncludeCycle) {
+		t.Error("failed to detect cycle", err)
+	}
+
+	if _, err := (&Loader{FS: fsys}).Load("missing.k"); err == nil {
+		t.Error("failed to fail")
+	}
+
+	d, err := (&Loader{FS: fsys, IncludeKey: "!include"}).Load("custom.k")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if d.Val("c") != "3" {
+		t.Error("failed to include with custom key")
+	}
+}
+
+func TestLoaderIncludeComment(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.k": {Data: []byte("[server]\n@include = s.k\n")},
+		"s.k":   {Data: []byte("port = 1\n# trailing comment\n")},
+	}
+
+	d, err := (&Loader{FS: fsys}).Load("app.k")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if d.Len() != 2 || len(d.EntryAt(1).Key) != 0 || d.EntryAt(1).Comment != "trailing comment" {
+		t.Error("invalid entries", d.Entries())
+	}
+
+	if len(d.EntriesOf("server")) != 0 {
+		t.Error("invalid key")
+	}
+}
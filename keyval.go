@@ -9,15 +9,19 @@ const (
 	OpenSectionChar  = '['
 	CloseSectionChar = ']'
 	CommentChar      = '#'
+	DeleteChar       = '-'
 	NewlineChar      = '\n'
 	SpaceChar        = ' '
 	TabChar          = '\t'
 )
 
+// Entry is a key-value pair with an optional comment. When Deleted is set, the entry is a tombstone: it tells
+// that the key doesn't exist anymore, and its value is ignored.
 type Entry struct {
 	Key     []string
 	Val     string
 	Comment string
+	Deleted bool
 }
 
 var (
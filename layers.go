@@ -0,0 +1,158 @@
+package keyval
+
+type MergePolicy int
+
+const (
+	MergeReplace MergePolicy = iota
+	MergeAppend
+)
+
+type layer struct {
+	name string
+	doc  *Document
+}
+
+// Layers merges documents in the order they were added, the later layers taking precedence. The policies are
+// applied by the longest matching key prefix, defaulting to MergeReplace. Deleted entries, and, when Tombstone
+// is not empty, entries having it as the value, delete the key from the result of the preceding layers.
+type Layers struct {
+	Policies  map[string]MergePolicy
+	Tombstone string
+	layers    []layer
+	merged    *Document
+	origin    map[*Entry]string
+}
+
+func (l *Layers) Add(name string, d *Document) {
+	l.layers = append(l.layers, layer{name: name, doc: d})
+	l.merged = nil
+}
+
+func (l *Layers) policyOf(key []string) MergePolicy {
+	for i := len(key); i >= 0; i-- {
+		if p, ok := l.Policies[JoinKey(key[:i])]; ok {
+			return p
+		}
+	}
+
+	return MergeReplace
+}
+
+func (l *Layers) tombstone(e *Entry) bool {
+	return e.Deleted || l.Tombstone != "" && e.Val == l.Tombstone
+}
+
+func groupEntries(d *Document) ([]string, map[string][]*Entry) {
+	var keys []string
+	groups := make(map[string][]*Entry)
+	for _, e := range d.Entries() {
+		if e == nil {
+			continue
+		}
+
+		ks := canonicalKey(e.Key)
+		if _, ok := groups[ks]; !ok {
+			keys = append(keys, ks)
+		}
+
+		groups[ks] = append(groups[ks], e)
+	}
+
+	return keys, groups
+}
+
+func (l *Layers) mergeGroup(result *Document, name string, group []*Entry) {
+	key := group[0].Key
+	contrib := group
+	deleted := false
+	for i := len(group) - 1; i >= 0; i-- {
+		if l.tombstone(group[i]) {
+			contrib = group[i+1:]
+			deleted = true
+			break
+		}
+	}
+
+	first, last := -1, -1
+	for i, e := range result.Entries() {
+		if KeyEq(e.Key, key) {
+			if first < 0 {
+				first = i
+			}
+
+			last = i
+		}
+	}
+
+	copies := make([]*Entry, len(contrib))
+	for i, e := range contrib {
+		copies[i] = copyEntry(e)
+		l.origin[copies[i]] = name
+	}
+
+	if first < 0 {
+		result.AppendEntry(copies...)
+		return
+	}
+
+	if !deleted && l.policyOf(key) == MergeAppend {
+		result.InsertEntry(last+1, copies...)
+		return
+	}
+
+	for _, e := range result.EntriesOf(key...) {
+		delete(l.origin, e)
+	}
+
+	result.DeleteOf(key...)
+	result.InsertEntry(first, copies...)
+}
+
+func (l *Layers) Merge() *Document {
+	result := &Document{}
+	l.origin = make(map[*Entry]string)
+	for _, li := range l.layers {
+		if li.doc == nil {
+			continue
+		}
+
+		keys, groups := groupEntries(li.doc)
+		for _, ks := range keys {
+			l.mergeGroup(result, li.name, groups[ks])
+		}
+	}
+
+	l.merged = result
+	return result.Copy()
+}
+
+func (l *Layers) EntryOrigin(e *Entry) string {
+	return l.origin[e]
+}
+
+func (l *Layers) OriginOf(key ...string) string {
+	if l.merged == nil {
+		l.Merge()
+	}
+
+	_, e := l.merged.EntryOf(key...)
+	if e == nil {
+		return ""
+	}
+
+	return l.origin[e]
+}
+
+func (l *Layers) Origin(key string) string {
+	return l.OriginOf(SplitKey(key)...)
+}
+
+func (d *Document) Merge(o ...*Document) {
+	l := &Layers{}
+	l.Add("", d)
+	for _, oi := range o {
+		l.Add("", oi)
+	}
+
+	d.ReplaceEntry(0, d.Len(), l.Merge().Entries()...)
+}
@@ -0,0 +1,154 @@
+package keyval
+
+import "testing"
+
+func TestLayersReplace(t *testing.T) {
+	defaults := &Document{}
+	defaults.Append("server.port", "8080")
+	defaults.Append("server.host", "a.example.org")
+	defaults.Append("server.host", "b.example.org")
+	defaults.Append("server.timeout", "3s")
+
+	env := &Document{}
+	env.Append("server.host", "c.example.org")
+	env.Append("server.debug", "true")
+
+	local := &Document{}
+	local.Append("server.port", "9000")
+
+	l := &Layers{}
+	l.Add("defaults", defaults)
+	l.Add("env", env)
+	l.Add("local", local)
+	d := l.Merge()
+
+	if d.Len() != 4 {
+		t.Error("invalid number of entries", d.Len())
+		return
+	}
+
+	if d.Val("server.port") != "9000" || l.Origin("server.port") != "local" {
+		t.Error("failed to override", d.Val("server.port"), l.Origin("server.port"))
+	}
+
+	hosts := d.Vals("server.host")
+	if len(hosts) != 1 || hosts[0] != "c.example.org" || l.Origin("server.host") != "env" {
+		t.Error("failed to replace list", hosts)
+	}
+
+	if d.Val("server.timeout") != "3s" || l.Origin("server.timeout") != "defaults" {
+		t.Error("failed to keep default")
+	}
+
+	if !KeyEq(d.EntryAt(0).Key, []string{"server", "port"}) {
+		t.Error("failed to keep order")
+	}
+
+	if defaults.Val("server.port") != "8080" {
+		t.Error("layer modified")
+	}
+}
+
+func TestLayersAppend(t *testing.T) {
+	defaults := &Document{}
+	defaults.Append("server.host", "a.example.org")
+	defaults.Append("server.port", "8080")
+
+	env := &Document{}
+	env.Append("server.host", "b.example.org")
+	env.Append("server.port", "9000")
+
+	l := &Layers{Policies: map[string]MergePolicy{"server.host": MergeAppend}}
+	l.Add("defaults", defaults)
+	l.Add("env", env)
+	d := l.Merge()
+
+	hosts := d.EntriesOf("server", "host")
+	if len(hosts) != 2 || hosts[0].Val != "a.example.org" || hosts[1].Val != "b.example.org" {
+		t.Error("failed to append list")
+		return
+	}
+
+	if l.EntryOrigin(hosts[0]) != "defaults" || l.EntryOrigin(hosts[1]) != "env" {
+		t.Error("invalid origin")
+	}
+
+	if len(d.Vals("server.port")) != 1 || d.Val("server.port") != "9000" {
+		t.Error("failed to replace")
+	}
+}
+
+func TestLayersTombstone(t *testing.T) {
+	defaults := &Document{}
+	defaults.Append("server.port", "8080")
+	defaults.Append("server.debug", "true")
+
+	local := &Document{}
+	local.Append("server.debug", "-")
+	local.Append("server.port", "-")
+	local.Append("server.port", "9000")
+
+	l := &Layers{Tombstone: "-"}
+	l.Add("defaults", defaults)
+	l.Add("local", local)
+	d := l.Merge()
+
+	if len(d.EntriesOf("server", "debug")) != 0 || l.Origin("server.debug") != "" {
+		t.Error("failed to delete")
+	}
+
+	if d.Len() != 1 || d.Val("server.port") != "9000" {
+		t.Error("failed to set after delete")
+	}
+}
+
+func TestLayersDeleted(t *testing.T) {
+	defaults := &Document{}
+	defaults.Append("server.port", "8080")
+	defaults.Append("server.debug", "true")
+
+	local := &Document{}
+	local.AppendDeleted("server.debug")
+
+	l := &Layers{}
+	l.Add("defaults", defaults)
+	l.Add("local", local)
+	d := l.Merge()
+
+	if d.Len() != 1 || d.Val("server.port") != "8080" {
+		t.Error("failed to delete", d.Entries())
+	}
+}
+
+func TestDocumentMerge(t *testing.T) {
+	d := &Document{}
+	d.Append("a", "1")
+	d.Append("b", "2")
+
+	o := &Document{}
+	o.Append("b", "3")
+	o.Append("c", "4")
+
+	d.Merge(o)
+	if d.Len() != 3 || d.Val("a") != "1" || d.Val("b") != "3" || d.Val("c") != "4" {
+		t.Error("failed to merge")
+	}
+}
+
+func TestLayersKeyParts(t *testing.T) {
+	base := &Document{}
+	base.AppendVal([]string{"a", "b"}, "1")
+	base.AppendVal([]string{"a.b"}, "2")
+
+	local := &Document{}
+	local.AppendVal([]string{"a.b"}, "3")
+
+	l := &Layers{}
+	l.Add("base", base)
+	l.Add("local", local)
+	d := l.Merge()
+
+	if d.Len() != 2 || d.ValOf("a", "b") != "1" || d.ValOf("a.b") != "3" {
+		t.Error("failed to separate keys", d.Entries())
+	}
+}
@@ -0,0 +1,72 @@
+package keyval
+
+import "io/fs"
+
+func expandPatterns(fsys fs.FS, patterns []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, p := range patterns {
+		matches := []string{p}
+		if hasGlobMeta(p) {
+			var err error
+			if matches, err = fs.Glob(fsys, p); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// LoadFS reads the files matching the patterns into a single document, in the order of the patterns, and the
+// files matching the same pattern in lexical order. A pattern without glob characters must match an existing
+// file. The include directives in the files are applied, as by Loader.
+func LoadFS(fsys fs.FS, patterns ...string) (*Document, error) {
+	files, err := expandPatterns(fsys, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Document{}
+	l := &Loader{FS: fsys}
+	for _, f := range files {
+		if err := l.load(d, f, nil, newLoadState()); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// LoadFS adds the files matching the patterns as separate layers, in the same order as the package level
+// LoadFS reads them. The layers are named by the file paths.
+func (l *Layers) LoadFS(fsys fs.FS, patterns ...string) error {
+	files, err := expandPatterns(fsys, patterns)
+	if err != nil {
+		return err
+	}
+
+	var docs []*Document
+	loader := &Loader{FS: fsys}
+	for _, f := range files {
+		d := &Document{}
+		if err := loader.load(d, f, nil, newLoadState()); err != nil {
+			return err
+		}
+
+		docs = append(docs, d)
+	}
+
+	for i, d := range docs {
+		l.Add(files[i], d)
+	}
+
+	return nil
+}
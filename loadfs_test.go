@@ -0,0 +1,82 @@
+package keyval
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.k":         {Data: []byte("port = 8080\n")},
+		"conf.d/20-b.k": {Data: []byte("port = 9002\n")},
+		"conf.d/10-a.k": {Data: []byte("port = 9001\nhost = example.org\n")},
+		"conf.d/readme": {Data: []byte("not keyval = true\n")},
+		"empty.d/.keep": {},
+	}
+
+	d, err := LoadFS(fsys, "app.k", "conf.d/*.k", "empty.d/*.k", "app.k")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	checkVals(t, d, "8080", "9001", "example.org", "9002")
+	if p := d.PositionOf(d.EntryAt(3)); p.File != "conf.d/20-b.k" || p.Line != 1 {
+		t.Error("invalid position", p)
+	}
+
+	if _, err := LoadFS(fsys, "missing.k"); err == nil {
+		t.Error("failed to fail")
+	}
+
+	if _, err := LoadFS(fsys, "conf.d/[.k"); err == nil {
+		t.Error("failed to fail")
+	}
+}
+
+func TestLayersLoadFS(t *testing.T) {
+	defaults := fstest.MapFS{
+		"defaults.k": {Data: []byte("port = 8080\nhost = localhost\n")},
+	}
+
+	local := fstest.MapFS{
+		"conf.d/a.k": {Data: []byte("port = 9001\n")},
+		"conf.d/b.k": {Data: []byte("host = example.org\n")},
+	}
+
+	l := &Layers{}
+	if err := l.LoadFS(defaults, "defaults.k"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := l.LoadFS(local, "conf.d/*.k"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	d := l.Merge()
+	if d.Val("port") != "9001" || d.Val("host") != "example.org" {
+		t.Error("failed to merge")
+	}
+
+	if l.Origin("port") != "conf.d/a.k" || l.Origin("host") != "conf.d/b.k" {
+		t.Error("invalid origin")
+	}
+}
+
+func TestLayersLoadFSGlobName(t *testing.T) {
+	fsys := fstest.MapFS{
+		"conf/[a].k": {Data: []byte("port = 9001\n")},
+	}
+
+	l := &Layers{}
+	if err := l.LoadFS(fsys, "conf/*.k"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if l.Merge().Val("port") != "9001" || l.Origin("port") != "conf/[a].k" {
+		t.Error("failed to load file with glob characters in its name")
+	}
+}
@@ -0,0 +1,122 @@
+package keyval
+
+import "errors"
+
+type MovePolicy int
+
+const (
+	// MoveFail fails when there are entries under the destination key.
+	MoveFail MovePolicy = iota
+
+	// MoveReplace deletes the entries under the destination key.
+	MoveReplace
+
+	// MoveMerge keeps the entries under the destination key, in their original position.
+	MoveMerge
+)
+
+var ErrDestinationExists = errors.New("destination exists")
+
+func rebase(key, from, to []string) []string {
+	k := make([]string, 0, len(to)+len(key)-len(from))
+	return append(append(k, to...), key[len(from):]...)
+}
+
+func (d *Document) prepareDestination(from, to []string, p MovePolicy) error {
+	conflict := func(e *Entry) bool { return hasPrefix(e.Key, to) && !hasPrefix(e.Key, from) }
+	switch p {
+	case MoveFail:
+		for _, e := range d.Entries() {
+			if e != nil && conflict(e) {
+				return ErrDestinationExists
+			}
+		}
+	case MoveReplace:
+		d.DeleteFunc(conflict)
+	}
+
+	return nil
+}
+
+// MoveOf changes the key prefix of the entries under from to the key to, keeping their position and comment.
+func (d *Document) MoveOf(from, to []string, p MovePolicy) error {
+	if KeyEq(from, to) {
+		return nil
+	}
+
+	d.Begin()
+	defer d.Commit()
+
+	if err := d.prepareDestination(from, to, p); err != nil {
+		return err
+	}
+
+	for i, e := range d.Entries() {
+		if e != nil && hasPrefix(e.Key, from) {
+			m := copyEntry(e)
+			m.Key = rebase(e.Key, from, to)
+			d.ReplaceEntry(i, 1, m)
+		}
+	}
+
+	return nil
+}
+
+// RenameOf changes the last part of the key of the entries with the key, and of the entries below it.
+func (d *Document) RenameOf(key []string, name string, p MovePolicy) error {
+	if len(key) == 0 {
+		return nil
+	}
+
+	to := make([]string, len(key))
+	copy(to, key)
+	to[len(to)-1] = name
+	return d.MoveOf(key, to, p)
+}
+
+// CopyTreeOf copies the entries under from to the key to, and inserts them after the last entry of the
+// source.
+func (d *Document) CopyTreeOf(from, to []string, p MovePolicy) error {
+	if KeyEq(from, to) {
+		return nil
+	}
+
+	d.Begin()
+	defer d.Commit()
+
+	if err := d.prepareDestination(from, to, p); err != nil {
+		return err
+	}
+
+	var (
+		copies []*Entry
+		last   int
+	)
+
+	for i, e := range d.Entries() {
+		if e != nil && hasPrefix(e.Key, from) {
+			c := copyEntry(e)
+			c.Key = rebase(e.Key, from, to)
+			copies = append(copies, c)
+			last = i
+		}
+	}
+
+	if len(copies) > 0 {
+		d.InsertEntry(last+1, copies...)
+	}
+
+	return nil
+}
+
+func (d *Document) Move(from, to string, p MovePolicy) error {
+	return d.MoveOf(SplitKey(from), SplitKey(to), p)
+}
+
+func (d *Document) Rename(key, name string, p MovePolicy) error {
+	return d.RenameOf(SplitKey(key), name, p)
+}
+
+func (d *Document) CopyTree(from, to string, p MovePolicy) error {
+	return d.CopyTreeOf(SplitKey(from), SplitKey(to), p)
+}
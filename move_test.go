@@ -0,0 +1,119 @@
+package keyval
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+const moveTestDoc = `db/host = db.example.org
+server/port = 8080
+# database port
+db/port = 5432
+#
+storage/db/port = 5433
+`
+
+func checkKeys(t *testing.T, d *Document, keys ...string) {
+	if d.Len() != len(keys) {
+		t.Error("invalid number of entries", d.Len(), len(keys))
+		return
+	}
+
+	for i, k := range keys {
+		if JoinKey(d.EntryAt(i).Key) != k {
+			t.Error(i, "invalid key", JoinKey(d.EntryAt(i).Key), k)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(moveTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if err := d.Move("db", "storage.db", MoveFail); err != ErrDestinationExists {
+		t.Error("failed to fail", err)
+	}
+
+	checkKeys(t, d, "db.host", "server.port", "db.port", "storage.db.port")
+
+	if err := d.Move("db", "storage.db", MoveMerge); err != nil {
+		t.Error(err)
+		return
+	}
+
+	checkKeys(t, d, "storage.db.host", "server.port", "storage.db.port", "storage.db.port")
+	if d.Val("storage.db.port") != "5433" || d.Comment("storage.db.host") != "" {
+		t.Error("failed to keep order")
+	}
+
+	d = &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(moveTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if err := d.Move("db", "storage.db", MoveReplace); err != nil {
+		t.Error(err)
+		return
+	}
+
+	checkKeys(t, d, "storage.db.host", "server.port", "storage.db.port")
+	if d.Val("storage.db.port") != "5432" || d.Comment("storage.db.port") != "database port" {
+		t.Error("failed to move")
+	}
+}
+
+func TestMoveUnder(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(moveTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if err := d.Move("db", "db.old", MoveFail); err != nil {
+		t.Error(err)
+		return
+	}
+
+	checkKeys(t, d, "db.old.host", "server.port", "db.old.port", "storage.db.port")
+}
+
+func TestRename(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(moveTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if err := d.Rename("db", "database", MoveFail); err != nil {
+		t.Error(err)
+		return
+	}
+
+	checkKeys(t, d, "database.host", "server.port", "database.port", "storage.db.port")
+	if err := d.Rename("server.port", "listen", MoveFail); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if d.Val("server.listen") != "8080" {
+		t.Error("failed to rename")
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(moveTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if err := d.CopyTree("db", "backup.db", MoveFail); err != nil {
+		t.Error(err)
+		return
+	}
+
+	checkKeys(t, d, "db.host", "server.port", "db.port", "backup.db.host", "backup.db.port", "storage.db.port")
+	d.SetVal("backup.db.port", "5434")
+	if d.Val("db.port") != "5432" || d.Comment("backup.db.port") != "database port" {
+		t.Error("failed to copy")
+	}
+
+	if err := d.CopyTree("db", "storage.db", MoveFail); err != ErrDestinationExists {
+		t.Error("failed to fail", err)
+	}
+}
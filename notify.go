@@ -0,0 +1,82 @@
+package keyval
+
+type ChangeType int
+
+const (
+	ChangeInsert ChangeType = iota
+	ChangeDelete
+	ChangeVal
+	ChangeComment
+)
+
+// Change describes a modification of a document. For inserted and deleted entries, Index is the position of
+// the entry before the deletion or after the insertion, and Old or New holds the value. For value and comment
+// changes, Old and New hold the previous and the current value or comment, and Index is -1.
+type Change struct {
+	Type  ChangeType
+	Key   []string
+	Old   string
+	New   string
+	Index int
+	Entry *Entry
+}
+
+type subscription struct {
+	prefix []string
+	f      func(Change)
+}
+
+func hasPrefix(key, prefix []string) bool {
+	return len(key) >= len(prefix) && KeyEq(key[:len(prefix)], prefix)
+}
+
+// Subscribe calls f on every change of the entries whose key starts with prefix. The callbacks are called
+// synchronously by the modifying call, including the changes made by Rollback, Undo and Redo. The returned
+// function cancels the subscription.
+func (d *Document) Subscribe(f func(Change), prefix ...string) func() {
+	p := make([]string, len(prefix))
+	copy(p, prefix)
+	s := &subscription{prefix: p, f: f}
+	d.subs = append(d.subs, s)
+
+	return func() {
+		for i, si := range d.subs {
+			if si == s {
+				d.subs = append(d.subs[:i:i], d.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Notify sends the changes to c. The sending blocks the modifying call, so c needs to be buffered or received
+// from another goroutine.
+func (d *Document) Notify(c chan<- Change, prefix ...string) func() {
+	return d.Subscribe(func(ch Change) { c <- ch }, prefix...)
+}
+
+func (d *Document) notify(c Change) {
+	for _, s := range d.subs {
+		if hasPrefix(c.Key, s.prefix) {
+			s.f(c)
+		}
+	}
+}
+
+func (d *Document) notifyReplace(at int, removed, inserted []*Entry) {
+	if len(d.subs) == 0 {
+		return
+	}
+
+	for i, e := range removed {
+		if e != nil {
+			d.notify(Change{Type: ChangeDelete, Key: e.Key, Old: e.Val, Index: at + i, Entry: e})
+		}
+	}
+
+	for i, e := range inserted {
+		if e != nil {
+			d.notify(Change{Type: ChangeInsert, Key: e.Key, New: e.Val, Index: at + i, Entry: e})
+		}
+	}
+}
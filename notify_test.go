@@ -0,0 +1,65 @@
+package keyval
+
+import "testing"
+
+func TestSubscribe(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8080")
+	d.Append("client.timeout", "3s")
+
+	var changes []Change
+	cancel := d.Subscribe(func(c Change) { changes = append(changes, c) }, "server")
+
+	d.Append("server.host", "example.org")
+	d.SetVal("server.port", "9000")
+	d.SetVal("client.timeout", "1s")
+	d.SetComment("server.port", "listen port")
+	d.Delete("server.host")
+
+	if len(changes) != 4 {
+		t.Error("invalid number of changes", len(changes))
+		return
+	}
+
+	if c := changes[0]; c.Type != ChangeInsert || JoinKey(c.Key) != "server.host" || c.New != "example.org" || c.Index != 2 {
+		t.Error("invalid insert change", c)
+	}
+
+	if c := changes[1]; c.Type != ChangeVal || c.Old != "8080" || c.New != "9000" {
+		t.Error("invalid value change", c)
+	}
+
+	if c := changes[2]; c.Type != ChangeComment || c.Old != "" || c.New != "listen port" {
+		t.Error("invalid comment change", c)
+	}
+
+	if c := changes[3]; c.Type != ChangeDelete || c.Old != "example.org" || c.Index != 2 {
+		t.Error("invalid delete change", c)
+	}
+
+	cancel()
+	d.SetVal("server.port", "9001")
+	if len(changes) != 4 {
+		t.Error("failed to cancel subscription")
+	}
+}
+
+func TestNotifyRollback(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8080")
+
+	c := make(chan Change, 4)
+	d.Notify(c)
+
+	d.Begin()
+	d.SetVal("server.port", "9000")
+	d.Rollback()
+
+	if ch := <-c; ch.Old != "8080" || ch.New != "9000" {
+		t.Error("invalid change", ch)
+	}
+
+	if ch := <-c; ch.Old != "9000" || ch.New != "8080" {
+		t.Error("invalid rollback change", ch)
+	}
+}
@@ -0,0 +1,178 @@
+package keyval
+
+import (
+	"errors"
+	"fmt"
+)
+
+type PatchOpType int
+
+const (
+	PatchSet PatchOpType = iota
+	PatchDelete
+	PatchAppend
+	PatchComment
+	PatchExpect
+	PatchAbsent
+)
+
+// A patch op is represented in keyval as an entry, whose first key part is the op name, and the rest is the
+// key that the op applies to, e.g: set/server/port = 9000
+type PatchOp struct {
+	Type PatchOpType
+	Key  []string
+	Val  string
+}
+
+type Patch []PatchOp
+
+type ConflictError struct {
+	Key      []string
+	Expected string
+	Actual   string
+	Missing  bool
+	Present  bool
+}
+
+var ErrInvalidPatch = errors.New("invalid patch")
+
+var patchOpNames = map[PatchOpType]string{
+	PatchSet:     "set",
+	PatchDelete:  "delete",
+	PatchAppend:  "append",
+	PatchComment: "comment",
+	PatchExpect:  "expect",
+	PatchAbsent:  "absent",
+}
+
+func (t PatchOpType) String() string {
+	return patchOpNames[t]
+}
+
+func (e *ConflictError) Error() string {
+	switch {
+	case e.Missing:
+		return fmt.Sprintf("patch conflict: %s: missing, expected: %q", JoinKey(e.Key), e.Expected)
+	case e.Present:
+		return fmt.Sprintf("patch conflict: %s: present, expected absent", JoinKey(e.Key))
+	default:
+		return fmt.Sprintf("patch conflict: %s: %q, expected: %q", JoinKey(e.Key), e.Actual, e.Expected)
+	}
+}
+
+func patchOpType(name string) (PatchOpType, bool) {
+	for t, n := range patchOpNames {
+		if n == name {
+			return t, true
+		}
+	}
+
+	return 0, false
+}
+
+func PatchOf(d *Document) (Patch, error) {
+	var p Patch
+	for _, e := range d.Entries() {
+		if e == nil || len(e.Key) == 0 {
+			continue
+		}
+
+		t, ok := patchOpType(e.Key[0])
+		if !ok || len(e.Key) < 2 {
+			return nil, ErrInvalidPatch
+		}
+
+		p = append(p, PatchOp{Type: t, Key: e.Key[1:], Val: e.Val})
+	}
+
+	return p, nil
+}
+
+func (p Patch) Document() *Document {
+	d := &Document{}
+	for _, op := range p {
+		d.AppendVal(append([]string{op.Type.String()}, op.Key...), op.Val)
+	}
+
+	return d
+}
+
+func checkOp(op PatchOp, e *Entry) error {
+	switch op.Type {
+	case PatchExpect:
+		if e == nil {
+			return &ConflictError{Key: op.Key, Expected: op.Val, Missing: true}
+		}
+
+		if e.Val != op.Val {
+			return &ConflictError{Key: op.Key, Expected: op.Val, Actual: e.Val}
+		}
+	case PatchAbsent:
+		if e != nil {
+			return &ConflictError{Key: op.Key, Actual: e.Val, Present: true}
+		}
+	}
+
+	return nil
+}
+
+// check verifies the expect and absent ops without changing the document. It tracks the effective entries
+// that the preceding ops of the patch would set or delete.
+func (d *Document) check(p Patch) error {
+	pending := make(map[string]*Entry)
+	for _, op := range p {
+		ck := canonicalKey(op.Key)
+		e, ok := pending[ck]
+		if !ok {
+			e = d.effectiveEntry(op.Key...)
+		}
+
+		switch op.Type {
+		case PatchSet, PatchAppend:
+			pending[ck] = &Entry{Key: op.Key, Val: op.Val}
+		case PatchDelete:
+			pending[ck] = nil
+		case PatchComment:
+		case PatchExpect, PatchAbsent:
+			if err := checkOp(op, e); err != nil {
+				return err
+			}
+		default:
+			return ErrInvalidPatch
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) applyOp(op PatchOp) {
+	switch op.Type {
+	case PatchSet:
+		if d.effectiveEntry(op.Key...) == nil {
+			d.AppendVal(op.Key, op.Val)
+		} else {
+			d.SetValOf(op.Key, op.Val)
+		}
+	case PatchDelete:
+		d.DeleteOf(op.Key...)
+	case PatchAppend:
+		d.AppendVal(op.Key, op.Val)
+	case PatchComment:
+		d.SetCommentOf(op.Key, op.Val)
+	}
+}
+
+// Apply applies the patch ops in order. The expect and absent ops are checked before any change, and when any
+// of them fails, the document is left unchanged, and the subscribers are not notified.
+func (d *Document) Apply(p Patch) error {
+	if err := d.check(p); err != nil {
+		return err
+	}
+
+	d.Begin()
+	for _, op := range p {
+		d.applyOp(op)
+	}
+
+	return d.Commit()
+}
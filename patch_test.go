@@ -0,0 +1,206 @@
+package keyval
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestPatchRoundtrip(t *testing.T) {
+	p := Patch{
+		{Type: PatchExpect, Key: []string{"server", "port"}, Val: "8080"},
+		{Type: PatchSet, Key: []string{"server", "port"}, Val: "9000"},
+		{Type: PatchDelete, Key: []string{"server", "debug"}},
+		{Type: PatchAppend, Key: []string{"server", "host"}, Val: "b.example.org"},
+		{Type: PatchComment, Key: []string{"server", "host"}, Val: "backend hosts"},
+		{Type: PatchAbsent, Key: []string{"server", "tls"}}}
+
+	buf := bytes.NewBuffer(nil)
+	if err := p.Document().WriteAll(buf); err != nil {
+		t.Error(err)
+		return
+	}
+
+	d := &Document{}
+	if err := d.ReadAll(buf); err != nil && err != io.EOF {
+		t.Error(err)
+		return
+	}
+
+	pback, err := PatchOf(d)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(pback) != len(p) {
+		t.Error("invalid number of ops", len(pback), len(p))
+		return
+	}
+
+	for i, op := range pback {
+		if op.Type != p[i].Type || !KeyEq(op.Key, p[i].Key) || op.Val != p[i].Val {
+			t.Error(i, "invalid op", op, p[i])
+		}
+	}
+}
+
+func TestPatchInvalid(t *testing.T) {
+	d := &Document{}
+	d.AppendVal([]string{"replace", "server", "port"}, "9000")
+	if _, err := PatchOf(d); err != ErrInvalidPatch {
+		t.Error("failed to fail", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8080")
+	d.Append("server.debug", "true")
+	d.Append("server.host", "a.example.org")
+
+	err := d.Apply(Patch{
+		{Type: PatchExpect, Key: []string{"server", "port"}, Val: "8080"},
+		{Type: PatchSet, Key: []string{"server", "port"}, Val: "9000"},
+		{Type: PatchSet, Key: []string{"server", "timeout"}, Val: "3s"},
+		{Type: PatchDelete, Key: []string{"server", "debug"}},
+		{Type: PatchAppend, Key: []string{"server", "host"}, Val: "b.example.org"},
+		{Type: PatchComment, Key: []string{"server", "host"}, Val: "backend hosts"}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if d.Val("server.port") != "9000" || d.Val("server.timeout") != "3s" {
+		t.Error("failed to set values")
+	}
+
+	if len(d.EntriesOf("server", "debug")) != 0 {
+		t.Error("failed to delete")
+	}
+
+	hosts := d.Vals("server.host")
+	if len(hosts) != 2 || hosts[0] != "a.example.org" || hosts[1] != "b.example.org" {
+		t.Error("failed to append", hosts)
+	}
+
+	if d.Comment("server.host") != "backend hosts" {
+		t.Error("failed to set comment")
+	}
+}
+
+func TestApplyConflict(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8081")
+	d.Append("server.debug", "true")
+	original := d.EntryAt(0)
+
+	err := d.Apply(Patch{
+		{Type: PatchDelete, Key: []string{"server", "debug"}},
+		{Type: PatchSet, Key: []string{"server", "port"}, Val: "9000"},
+		{Type: PatchExpect, Key: []string{"server", "port"}, Val: "8080"}})
+	if _, ok := err.(*ConflictError); !ok {
+		t.Error("failed to detect conflict", err)
+		return
+	}
+
+	err = d.Apply(Patch{
+		{Type: PatchDelete, Key: []string{"server", "debug"}},
+		{Type: PatchExpect, Key: []string{"server", "port"}, Val: "8080"},
+		{Type: PatchSet, Key: []string{"server", "port"}, Val: "9000"}})
+	if _, ok := err.(*ConflictError); !ok {
+		t.Error("failed to detect conflict", err)
+		return
+	}
+
+	if d.Len() != 2 || d.EntryAt(0) != original || original.Val != "8081" || d.Val("server.debug") != "true" {
+		t.Error("document modified on conflict")
+	}
+
+	err = d.Apply(Patch{{Type: PatchAbsent, Key: []string{"server", "debug"}}})
+	if cerr, ok := err.(*ConflictError); !ok || !cerr.Present {
+		t.Error("failed to detect conflict", err)
+	}
+
+	err = d.Apply(Patch{{Type: PatchExpect, Key: []string{"server", "host"}}})
+	if cerr, ok := err.(*ConflictError); !ok || !cerr.Missing {
+		t.Error("failed to detect conflict", err)
+	}
+}
+
+func TestApplyKeepsEntries(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString("a = 1\nb = 2\n")); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+
+	b := d.EntryAt(1)
+	var changes []Change
+	d.Subscribe(func(c Change) { changes = append(changes, c) })
+
+	if err := d.Apply(Patch{{Type: PatchSet, Key: []string{"a"}, Val: "3"}}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if d.EntryAt(1) != b || d.LineOf(b) != 2 {
+		t.Error("failed to keep entries")
+	}
+
+	if len(changes) != 1 || changes[0].Type != ChangeVal {
+		t.Error("invalid changes", changes)
+	}
+}
+
+func TestApplyConflictNotNotified(t *testing.T) {
+	d := &Document{}
+	d.Append("a", "1")
+	d.Append("b", "y")
+
+	var changes []Change
+	d.Subscribe(func(c Change) { changes = append(changes, c) })
+
+	err := d.Apply(Patch{
+		{Type: PatchSet, Key: []string{"a"}, Val: "2"},
+		{Type: PatchExpect, Key: []string{"b"}, Val: "x"}})
+	if _, ok := err.(*ConflictError); !ok {
+		t.Error("failed to detect conflict", err)
+	}
+
+	if len(changes) != 0 || d.Val("a") != "1" {
+		t.Error("conflicting patch applied", changes)
+	}
+
+	err = d.Apply(Patch{
+		{Type: PatchDelete, Key: []string{"b"}},
+		{Type: PatchAbsent, Key: []string{"b"}},
+		{Type: PatchAppend, Key: []string{"c"}, Val: "3"},
+		{Type: PatchExpect, Key: []string{"c"}, Val: "3"}})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestApplyDeleted(t *testing.T) {
+	d := &Document{}
+	d.Append("a", "1")
+	d.AppendDeleted("a")
+
+	if err := d.Apply(Patch{{Type: PatchAbsent, Key: []string{"a"}}}); err != nil {
+		t.Error("invalid conflict", err)
+	}
+
+	err := d.Apply(Patch{{Type: PatchExpect, Key: []string{"a"}, Val: ""}})
+	if cerr, ok := err.(*ConflictError); !ok || !cerr.Missing {
+		t.Error("failed to detect conflict", err)
+	}
+
+	if err := d.Apply(Patch{{Type: PatchSet, Key: []string{"a"}, Val: "2"}}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if v, ok := d.Lookup("a"); !ok || v != "2" {
+		t.Error("failed to set deleted key", v, ok)
+	}
+}
@@ -0,0 +1,131 @@
+package keyval
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	anyPartPattern   = "*"
+	anyPartsPattern  = "**"
+	regexpPartPrefix = "~"
+)
+
+type patternPart struct {
+	anyParts bool
+	glob     string
+	rx       *regexp.Regexp
+}
+
+// Pattern matches keys part by part. A '*' part matches any single key part, '**' matches zero or more key
+// parts, a part starting with '~' is a regular expression matching the whole key part, and any other part is
+// matched as a glob by path.Match.
+type Pattern struct {
+	parts []patternPart
+}
+
+func CompilePattern(part ...string) (*Pattern, error) {
+	p := &Pattern{}
+	for _, pi := range part {
+		switch {
+		case pi == anyPartsPattern:
+			p.parts = append(p.parts, patternPart{anyParts: true})
+		case strings.HasPrefix(pi, regexpPartPrefix):
+			rx, err := regexp.Compile("^(?:" + pi[len(regexpPartPrefix):] + ")$")
+			if err != nil {
+				return nil, err
+			}
+
+			p.parts = append(p.parts, patternPart{rx: rx})
+		default:
+			if _, err := path.Match(pi, ""); err != nil {
+				return nil, err
+			}
+
+			p.parts = append(p.parts, patternPart{glob: pi})
+		}
+	}
+
+	return p, nil
+}
+
+// ParsePattern splits the pattern at '.' and compiles the parts. An escaped '\.' stands for a '.' inside a
+// part. In regexp parts, the escape is kept, so '\.' matches a literal '.'.
+func ParsePattern(pattern string) (*Pattern, error) {
+	var (
+		parts   []string
+		current []byte
+	)
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == EscapeChar && i+1 < len(pattern) && pattern[i+1] == '.':
+			if strings.HasPrefix(string(current), regexpPartPrefix) {
+				current = append(current, EscapeChar)
+			}
+
+			current = append(current, '.')
+			i++
+		case c == '.':
+			parts = append(parts, string(current))
+			current = nil
+		default:
+			current = append(current, c)
+		}
+	}
+
+	return CompilePattern(append(parts, string(current))...)
+}
+
+func (pp patternPart) match(part string) bool {
+	if pp.rx != nil {
+		return pp.rx.MatchString(part)
+	}
+
+	m, _ := path.Match(pp.glob, part)
+	return m
+}
+
+func matchParts(parts []patternPart, key []string) bool {
+	if len(parts) == 0 {
+		return len(key) == 0
+	}
+
+	if parts[0].anyParts {
+		for i := 0; i <= len(key); i++ {
+			if matchParts(parts[1:], key[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return len(key) > 0 && parts[0].match(key[0]) && matchParts(parts[1:], key[1:])
+}
+
+func (p *Pattern) Match(key []string) bool {
+	return matchParts(p.parts, key)
+}
+
+func (d *Document) Match(p *Pattern) []*Entry {
+	var entries []*Entry
+	for _, e := range d.Entries() {
+		if e != nil && p.Match(e.Key) {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries
+}
+
+func (d *Document) Query(pattern string) ([]*Entry, error) {
+	p, err := ParsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Match(p), nil
+}
@@ -0,0 +1,78 @@
+package keyval
+
+import "testing"
+
+func TestPatternMatch(t *testing.T) {
+	for i, item := range []struct {
+		pattern string
+		key     []string
+		match   bool
+	}{
+		{"servers.web.port", []string{"servers", "web", "port"}, true},
+		{"servers.web.port", []string{"servers", "web"}, false},
+		{"servers.*.port", []string{"servers", "web", "port"}, true},
+		{"servers.*.port", []string{"servers", "port"}, false},
+		{"servers.*.port", []string{"servers", "web", "api", "port"}, false},
+		{"**.timeout", []string{"timeout"}, true},
+		{"**.timeout", []string{"client", "http", "timeout"}, true},
+		{"**.timeout", []string{"client", "timeout", "unit"}, false},
+		{"client.**", []string{"client"}, true},
+		{"client.**", []string{"client", "http", "timeout"}, true},
+		{"servers.web*.port", []string{"servers", "web1", "port"}, true},
+		{"servers.~web[0-9]+.port", []string{"servers", "web12", "port"}, true},
+		{"servers.~web[0-9]+.port", []string{"servers", "web12a", "port"}, false},
+		{`hosts.example\.org`, []string{"hosts", "example.org"}, true},
+		{`hosts.~[a-z]+\.org`, []string{"hosts", "example.org"}, true},
+		{`hosts.~[a-z]+\.org`, []string{"hosts", "example", "org"}, false},
+		{`hosts.~a\.b`, []string{"hosts", "a.b"}, true},
+		{`hosts.~a\.b`, []string{"hosts", "axb"}, false},
+	} {
+		p, err := ParsePattern(item.pattern)
+		if err != nil {
+			t.Error(i, err)
+			continue
+		}
+
+		if p.Match(item.key) != item.match {
+			t.Error(i, "failed to match", item.pattern, item.key)
+		}
+	}
+}
+
+func TestPatternInvalid(t *testing.T) {
+	if _, err := ParsePattern("servers.~web[.port"); err == nil {
+		t.Error("failed to fail")
+	}
+
+	if _, err := ParsePattern("servers.web[.port"); err == nil {
+		t.Error("failed to fail")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	d := &Document{}
+	d.Append("servers.web.port", "8080")
+	d.Append("servers.web.timeout", "3s")
+	d.Append("servers.api.port", "9090")
+	d.Append("client.timeout", "1s")
+
+	entries, err := d.Query("servers.*.port")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(entries) != 2 || entries[0].Val != "8080" || entries[1].Val != "9090" {
+		t.Error("failed to query")
+	}
+
+	entries, err = d.Query("**.timeout")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(entries) != 2 || entries[0].Val != "3s" || entries[1].Val != "1s" {
+		t.Error("failed to query")
+	}
+}
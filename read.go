@@ -13,6 +13,8 @@ type readEntry struct {
 	section [][]byte
 	key     [][]byte
 	val     []byte
+	deleted bool
+	line    int
 }
 
 type ByteReader interface {
@@ -31,9 +33,12 @@ type EntryReader struct {
 	key            [][]byte
 	currentKey     []byte
 	val            []byte
+	deleteMark     bool
 	whitespace     []byte
 	commentApplied bool
 	sectionApplied bool
+	line           int
+	lastLine       int
 	err            error
 }
 
@@ -53,7 +58,7 @@ func NewEntryReader(r io.Reader) *EntryReader {
 		return &EntryReader{}
 	}
 
-	er := &EntryReader{state: stateInitial}
+	er := &EntryReader{state: stateInitial, line: 1}
 
 	br, ok := r.(ByteReader)
 	if !ok {
@@ -130,9 +135,12 @@ func (r *EntryReader) completeEntry() {
 		comment: r.comment,
 		section: r.section,
 		key:     r.key,
-		val:     r.val})
+		val:     r.val,
+		deleted: r.deleteMark && len(r.val) == 1 && r.val[0] == DeleteChar,
+		line:    r.line})
 	r.key = nil
 	r.val = nil
+	r.deleteMark = false
 	r.commentApplied = true
 	r.sectionApplied = true
 }
@@ -153,11 +161,18 @@ func (r *EntryReader) fetchEntry() *Entry {
 
 	var next *readEntry
 	next, r.entries = r.entries[0], r.entries[1:]
+	r.lastLine = next.line
 
-	return &Entry{
+	e := &Entry{
 		Key:     mergeKey(next.section, next.key),
 		Val:     string(next.val),
-		Comment: string(next.comment)}
+		Comment: string(next.comment),
+		Deleted: next.deleted}
+	if e.Deleted {
+		e.Val = ""
+	}
+
+	return e
 }
 
 func (r *EntryReader) hasRemainderSection() bool {
@@ -200,6 +215,11 @@ func (r *EntryReader) eofResult() (*Entry, error) {
 	return last, err
 }
 
+// Line returns the line number of the last entry returned by ReadEntry, where the entry was completed.
+func (r *EntryReader) Line() int {
+	return r.lastLine
+}
+
 func (r *EntryReader) ReadEntry() (*Entry, error) {
 	if r.reader == nil {
 		return nil, nil
@@ -236,6 +256,10 @@ func (r *EntryReader) ReadEntry() (*Entry, error) {
 		}
 
 		r.acceptChar(c)
+		if newline(c) {
+			r.line++
+		}
+
 		next := r.fetchEntry()
 		if next != nil {
 			return next, nil
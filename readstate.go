@@ -47,8 +47,11 @@ func (r *EntryReader) acceptChar(c byte) {
 			r.state = stateKey
 			r.keyWhitespace()
 			r.appendKey(c)
-		case stateValueInitial, stateValue:
+		case stateValueInitial:
 			r.state = stateValue
+			r.deleteMark = c == DeleteChar
+			r.appendValue(c)
+		case stateValue:
 			r.appendValue(c)
 		case stateValueOrElse:
 			r.state = stateValue
@@ -0,0 +1,172 @@
+package keyval
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	refStart     = "${"
+	refEnd       = "}"
+	refEscaped   = "$${"
+	envRefPrefix = "env:"
+)
+
+var (
+	ErrInvalidReference = errors.New("invalid reference")
+	ErrUnresolved       = errors.New("unresolved reference")
+	ErrReferenceCycle   = errors.New("reference cycle")
+)
+
+// Resolver expands the references in the values. A reference in the form of ${server.host} is replaced by the
+// effective value of the referenced key, itself expanded, and ${env:HOME} by the environment variable. The
+// sequence $${ stands for a literal ${.
+type Resolver struct {
+	// LookupEnv, when set, is used instead of os.LookupEnv.
+	LookupEnv func(string) (string, bool)
+
+	doc      *Document
+	resolved map[string]string
+	pending  map[string]bool
+}
+
+func (r *Resolver) lookupEnv(name string) (string, bool) {
+	if r.LookupEnv != nil {
+		return r.LookupEnv(name)
+	}
+
+	return os.LookupEnv(name)
+}
+
+func (r *Resolver) reset(d *Document) {
+	r.doc = d
+	r.resolved = make(map[string]string)
+	r.pending = make(map[string]bool)
+}
+
+func (r *Resolver) ref(ref string) (string, error) {
+	if strings.HasPrefix(ref, envRefPrefix) {
+		v, ok := r.lookupEnv(ref[len(envRefPrefix):])
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnresolved, ref)
+		}
+
+		return v, nil
+	}
+
+	key := SplitKey(ref)
+	ck := canonicalKey(key)
+	if v, ok := r.resolved[ck]; ok {
+		return v, nil
+	}
+
+	if r.pending[ck] {
+		return "", fmt.Errorf("%w: %s", ErrReferenceCycle, ref)
+	}
+
+	e := r.doc.effectiveEntry(key...)
+	if e == nil {
+		return "", fmt.Errorf("%w: %s", ErrUnresolved, ref)
+	}
+
+	r.pending[ck] = true
+	v, err := r.expand(e.Val)
+	delete(r.pending, ck)
+	if err != nil {
+		return "", err
+	}
+
+	r.resolved[ck] = v
+	return v, nil
+}
+
+func (r *Resolver) expand(val string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(val, "$")
+		if i < 0 {
+			b.WriteString(val)
+			return b.String(), nil
+		}
+
+		b.WriteString(val[:i])
+		val = val[i:]
+		switch {
+		case strings.HasPrefix(val, refEscaped):
+			b.WriteString(refStart)
+			val = val[len(refEscaped):]
+		case strings.HasPrefix(val, refStart):
+			end := strings.Index(val, refEnd)
+			if end < 0 {
+				return "", ErrInvalidReference
+			}
+
+			v, err := r.ref(val[len(refStart):end])
+			if err != nil {
+				return "", err
+			}
+
+			b.WriteString(v)
+			val = val[end+len(refEnd):]
+		default:
+			b.WriteString("$")
+			val = val[1:]
+		}
+	}
+}
+
+// ExpandOf returns the expanded effective value of a key.
+func (r *Resolver) ExpandOf(d *Document, key ...string) (string, error) {
+	r.reset(d)
+	e := d.effectiveEntry(key...)
+	if e == nil {
+		return "", &KeyError{Key: key, Err: ErrMissingKey}
+	}
+
+	v, err := r.expand(e.Val)
+	if err != nil {
+		return "", &KeyError{Key: key, Val: e.Val, Err: err}
+	}
+
+	return v, nil
+}
+
+func (r *Resolver) Expand(d *Document, key string) (string, error) {
+	return r.ExpandOf(d, SplitKey(key)...)
+}
+
+// Resolve expands the values of all the entries in the document. When a reference cannot be resolved, the
+// document is left unchanged, and the returned error points to the referencing key.
+func (r *Resolver) Resolve(d *Document) error {
+	r.reset(d)
+	entries := d.Entries()
+	vals := make([]string, len(entries))
+	for i, e := range entries {
+		if e == nil {
+			continue
+		}
+
+		v, err := r.expand(e.Val)
+		if err != nil {
+			return &KeyError{Key: e.Key, Val: e.Val, Err: err}
+		}
+
+		vals[i] = v
+	}
+
+	d.Begin()
+	defer d.Commit()
+	for i, e := range entries {
+		if e != nil && vals[i] != e.Val {
+			d.setField(e, &e.Val, vals[i])
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) Resolve() error {
+	return (&Resolver{}).Resolve(d)
+}
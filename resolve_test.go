@@ -0,0 +1,87 @@
+package keyval
+
+import (
+	"errors"
+	"testing"
+)
+
+func testEnv(name string) (string, bool) {
+	if name == "HOME" {
+		return "/home/test", true
+	}
+
+	return "", false
+}
+
+func TestResolve(t *testing.T) {
+	d := &Document{}
+	d.Append("base", "/srv")
+	d.Append("data", "${base}/data")
+	d.Append("cache", "${data}/cache")
+	d.Append("home", "${env:HOME}/.config")
+	d.Append("literal", "$${base} costs $5")
+	d.Append("base", "/opt")
+
+	r := &Resolver{LookupEnv: testEnv}
+	if v, err := r.Expand(d, "cache"); err != nil || v != "/opt/data/cache" {
+		t.Error("failed to expand", v, err)
+	}
+
+	if err := r.Resolve(d); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if d.Val("cache") != "/opt/data/cache" || d.Val("home") != "/home/test/.config" {
+		t.Error("failed to resolve")
+	}
+
+	if d.Val("literal") != "${base} costs $5" {
+		t.Error("failed to escape", d.Val("literal"))
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	for i, item := range []struct {
+		val string
+		key string
+		err error
+	}{
+		{"${missing}", "a", ErrUnresolved},
+		{"${env:MISSING}", "a", ErrUnresolved},
+		{"${b", "a", ErrInvalidReference},
+		{"${b}", "a", ErrReferenceCycle},
+	} {
+		d := &Document{}
+		d.Append("a", item.val)
+		d.Append("b", "${c}")
+		d.Append("c", "${b}")
+
+		err := (&Resolver{LookupEnv: testEnv}).Resolve(d)
+		kerr, ok := err.(*KeyError)
+		if !ok || JoinKey(kerr.Key) != item.key || !errors.Is(err, item.err) {
+			t.Error(i, "failed to fail", err)
+			continue
+		}
+
+		if d.Val("a") != item.val {
+			t.Error(i, "document changed")
+		}
+	}
+}
+
+func TestResolveDeleted(t *testing.T) {
+	d := &Document{}
+	d.Append("a", "1")
+	d.AppendDeleted("a")
+	d.Append("b", "${a}")
+
+	r := &Resolver{}
+	if _, err := r.Expand(d, "b"); !errors.Is(err, ErrUnresolved) {
+		t.Error("failed to fail", err)
+	}
+
+	if _, err := r.Expand(d, "a"); !errors.Is(err, ErrMissingKey) {
+		t.Error("failed to fail", err)
+	}
+}
@@ -0,0 +1,419 @@
+package keyval
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema declares the allowed keys of a document, and constraints on their values. A schema is itself a
+// keyval document, where the last part of each key is a property, and the preceding parts are a key pattern,
+// as accepted by CompilePattern, e.g:
+//
+//	strict = true
+//
+//	[server/port]
+//	type = int
+//	min = 1
+//	max = 65535
+//	required = true
+//
+//	[servers/*/host]
+//	regexp = \[a-z.]+
+//	max-count = 1
+//
+// The properties of a key pattern are: type (string, int, float, bool, duration, time, size), required, enum
+// (comma separated, or repeated), min and max (compared to the length for strings), regexp (matching the
+// whole value), min-count and max-count (the number of entries with the same key), and default (applied by
+// ApplyDefaults, only for patterns without wildcards). The root level property 'strict' makes the keys not
+// matching any of the patterns invalid.
+type Schema struct {
+	Strict bool
+	rules  []*schemaRule
+}
+
+type schemaRule struct {
+	key      []string
+	pattern  *Pattern
+	typ      string
+	required bool
+	enum     []string
+	min      *float64
+	max      *float64
+	rx       *regexp.Regexp
+	minCount int
+	maxCount int
+	def      *string
+}
+
+type Violation struct {
+	Key     []string
+	File    string
+	Line    int
+	Message string
+}
+
+type ValidationError []Violation
+
+var (
+	ErrInvalidSchema = errors.New("invalid schema")
+	errInvalidType   = errors.New("invalid type")
+)
+
+var valueTypes = map[string]func(string) (float64, error){
+	"string": func(s string) (float64, error) {
+		return float64(utf8.RuneCountInString(s)), nil
+	},
+	"int": func(s string) (float64, error) {
+		i, err := strconv.ParseInt(s, 10, 64)
+		return float64(i), err
+	},
+	"float": func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	},
+	"bool": func(s string) (float64, error) {
+		_, err := parseBool(s)
+		return 0, err
+	},
+	"duration": func(s string) (float64, error) {
+		d, err := time.ParseDuration(s)
+		return float64(d), err
+	},
+	"time": func(s string) (float64, error) {
+		t, err := time.Parse(time.RFC3339, s)
+		return float64(t.Unix()), err
+	},
+	"size": func(s string) (float64, error) {
+		i, err := parseSize(s)
+		return float64(i), err
+	},
+}
+
+func (v Violation) Error() string {
+	switch {
+	case v.File != "":
+		return fmt.Sprintf("%s:%d: %s: %s", v.File, v.Line, JoinKey(v.Key), v.Message)
+	case v.Line > 0:
+		return fmt.Sprintf("line %d: %s: %s", v.Line, JoinKey(v.Key), v.Message)
+	default:
+		return fmt.Sprintf("%s: %s", JoinKey(v.Key), v.Message)
+	}
+}
+
+func (e ValidationError) Error() string {
+	s := make([]string, len(e))
+	for i, v := range e {
+		s[i] = v.Error()
+	}
+
+	return strings.Join(s, "\n")
+}
+
+func schemaError(key []string, err error) error {
+	return &KeyError{Key: key, Err: err}
+}
+
+func (r *schemaRule) parseLimit(key []string, val string) (*float64, error) {
+	parse := valueTypes[r.typ]
+	if r.typ == "string" {
+		parse = valueTypes["int"]
+	}
+
+	f, err := parse(val)
+	if err != nil {
+		return nil, schemaError(key, err)
+	}
+
+	return &f, nil
+}
+
+func parseRule(d *Document, key []string) (*schemaRule, error) {
+	p, err := CompilePattern(key...)
+	if err != nil {
+		return nil, schemaError(key, err)
+	}
+
+	r := &schemaRule{key: key, pattern: p, typ: "string", minCount: -1, maxCount: -1}
+	prop := func(name string) []string { return append(append([]string(nil), key...), name) }
+
+	if v, ok := d.LookupOf(prop("type")...); ok {
+		if _, ok := valueTypes[v]; !ok {
+			return nil, schemaError(prop("type"), errInvalidType)
+		}
+
+		r.typ = v
+	}
+
+	if _, ok := d.LookupOf(prop("required")...); ok {
+		if r.required, err = d.BoolOf(prop("required")...); err != nil {
+			return nil, err
+		}
+	}
+
+	r.enum = d.StringsOf(prop("enum")...)
+
+	if v, ok := d.LookupOf(prop("min")...); ok {
+		if r.min, err = r.parseLimit(prop("min"), v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := d.LookupOf(prop("max")...); ok {
+		if r.max, err = r.parseLimit(prop("max"), v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := d.LookupOf(prop("regexp")...); ok {
+		if r.rx, err = regexp.Compile("^(?:" + v + ")$"); err != nil {
+			return nil, schemaError(prop("regexp"), err)
+		}
+	}
+
+	if v, ok := d.LookupOf(prop("default")...); ok {
+		if _, err := valueTypes[r.typ](v); err != nil {
+			return nil, schemaError(prop("default"), err)
+		}
+
+		r.def = &v
+	}
+
+	if _, ok := d.LookupOf(prop("min-count")...); ok {
+		if r.minCount, err = d.IntOf(prop("min-count")...); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := d.LookupOf(prop("max-count")...); ok {
+		if r.maxCount, err = d.IntOf(prop("max-count")...); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+var schemaProps = map[string]bool{
+	"type":      true,
+	"required":  true,
+	"enum":      true,
+	"min":       true,
+	"max":       true,
+	"regexp":    true,
+	"min-count": true,
+	"max-count": true,
+	"default":   true,
+}
+
+func ParseSchema(d *Document) (*Schema, error) {
+	s := &Schema{}
+	seen := make(map[string]bool)
+	for _, e := range d.Entries() {
+		if e == nil || len(e.Key) == 0 || len(e.Key) == 1 && e.Key[0] == "" {
+			continue
+		}
+
+		if len(e.Key) == 1 {
+			if e.Key[0] != "strict" {
+				return nil, schemaError(e.Key, ErrInvalidSchema)
+			}
+
+			continue
+		}
+
+		prop := e.Key[len(e.Key)-1]
+		if !schemaProps[prop] {
+			return nil, schemaError(e.Key, ErrInvalidSchema)
+		}
+
+		key := e.Key[:len(e.Key)-1]
+		ck := canonicalKey(key)
+		if seen[ck] {
+			continue
+		}
+
+		seen[ck] = true
+		r, err := parseRule(d, key)
+		if err != nil {
+			return nil, err
+		}
+
+		s.rules = append(s.rules, r)
+	}
+
+	if _, ok := d.Lookup("strict"); ok {
+		strict, err := d.Bool("strict")
+		if err != nil {
+			return nil, err
+		}
+
+		s.Strict = strict
+	}
+
+	return s, nil
+}
+
+func (r *schemaRule) literal() bool {
+	for _, k := range r.key {
+		if k == anyPartsPattern || strings.HasPrefix(k, regexpPartPrefix) || strings.ContainsAny(k, "*?[\\") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ApplyDefaults appends an entry with the default value for every key that has a default in the schema and is
+// missing from the document. The appended entries are marked as defaulted, and returned.
+func (d *Document) ApplyDefaults(s *Schema) []*Entry {
+	d.Begin()
+	defer d.Commit()
+
+	var applied []*Entry
+	for _, r := range s.rules {
+		if r.def == nil || !r.literal() {
+			continue
+		}
+
+		if e := d.effectiveEntry(r.key...); e != nil {
+			continue
+		}
+
+		e := &Entry{Key: append([]string(nil), r.key...), Val: *r.def}
+		d.AppendEntry(e)
+		d.setDefaulted(e)
+		applied = append(applied, e)
+	}
+
+	return applied
+}
+
+// IsDefault tells whether the entry was added by ApplyDefaults.
+func (d *Document) IsDefault(e *Entry) bool {
+	return d.defaulted[e]
+}
+
+func ReadSchema(r io.Reader) (*Schema, error) {
+	d := &Document{}
+	if err := d.ReadAll(r); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return ParseSchema(d)
+}
+
+func (r *schemaRule) validateVal(e *Entry, report func(*Entry, []string, string)) {
+	n, err := valueTypes[r.typ](e.Val)
+	if err != nil {
+		report(e, e.Key, fmt.Sprintf("invalid %s: %q", r.typ, e.Val))
+		return
+	}
+
+	if len(r.enum) > 0 {
+		found := false
+		for _, ev := range r.enum {
+			if ev == e.Val {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			report(e, e.Key, fmt.Sprintf("value not allowed: %q", e.Val))
+		}
+	}
+
+	if r.min != nil && n < *r.min {
+		report(e, e.Key, fmt.Sprintf("value below minimum: %q", e.Val))
+	}
+
+	if r.max != nil && n > *r.max {
+		report(e, e.Key, fmt.Sprintf("value above maximum: %q", e.Val))
+	}
+
+	if r.rx != nil && !r.rx.MatchString(e.Val) {
+		report(e, e.Key, fmt.Sprintf("value not matching %s: %q", r.rx, e.Val))
+	}
+}
+
+func (r *schemaRule) validate(d *Document, report func(*Entry, []string, string)) {
+	var (
+		keys   []string
+		counts = make(map[string][]*Entry)
+	)
+
+	for _, e := range d.Match(r.pattern) {
+		if e.Deleted {
+			continue
+		}
+
+		ck := canonicalKey(e.Key)
+		if _, ok := counts[ck]; !ok {
+			keys = append(keys, ck)
+		}
+
+		counts[ck] = append(counts[ck], e)
+		r.validateVal(e, report)
+	}
+
+	if r.required && len(keys) == 0 {
+		report(nil, r.key, "missing required key")
+	}
+
+	for _, ck := range keys {
+		entries := counts[ck]
+		last := entries[len(entries)-1]
+		if r.minCount >= 0 && len(entries) < r.minCount {
+			report(last, last.Key, fmt.Sprintf("too few entries: %d", len(entries)))
+		}
+
+		if r.maxCount >= 0 && len(entries) > r.maxCount {
+			report(last, last.Key, fmt.Sprintf("too many entries: %d", len(entries)))
+		}
+	}
+}
+
+// Validate checks the document against the schema, and returns a ValidationError with all the violations, or
+// nil, when the document is valid.
+func (d *Document) Validate(s *Schema) error {
+	var violations ValidationError
+	report := func(e *Entry, key []string, message string) {
+		p := d.PositionOf(e)
+		violations = append(violations, Violation{Key: key, File: p.File, Line: p.Line, Message: message})
+	}
+
+	for _, r := range s.rules {
+		r.validate(d, report)
+	}
+
+	if s.Strict {
+		for _, e := range d.Entries() {
+			if e == nil || len(e.Key) == 0 || e.Deleted {
+				continue
+			}
+
+			known := false
+			for _, r := range s.rules {
+				if r.pattern.Match(e.Key) {
+					known = true
+					break
+				}
+			}
+
+			if !known {
+				report(e, e.Key, "unknown key")
+			}
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return violations
+}
@@ -0,0 +1,157 @@
+package keyval
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+const testSchema = `
+strict = true
+
+[server/port]
+type = int
+min = 1
+max = 65535
+required = true
+
+[server/mode]
+enum = debug, release
+
+[server/cache]
+type = size
+max = 1GB
+
+[servers/*/host]
+regexp = \[a-z.]+
+max-count = 1
+
+[client/timeout]
+type = duration
+required = true`
+
+func TestValidateValid(t *testing.T) {
+	s, err := ReadSchema(bytes.NewBufferString(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Document{}
+	err = d.ReadAll(bytes.NewBufferString(`[server]
+port = 8080
+mode = debug
+cache = 512MB
+[servers/web]
+host = example.org
+[client]
+timeout = 3s`))
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+
+	if err := d.Validate(s); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateViolations(t *testing.T) {
+	s, err := ReadSchema(bytes.NewBufferString(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Document{}
+	err = d.ReadAll(bytes.NewBufferString(`[server]
+port = 80000
+mode = test
+cache = 2GB
+[servers/web]
+host = Example.org
+host = example.org
+[unknown]
+key = value`))
+	if err != nil && err != io.EOF {
+		t.Error(err)
+		return
+	}
+
+	err = d.Validate(s)
+	violations, ok := err.(ValidationError)
+	if !ok {
+		t.Error("failed to fail", err)
+		return
+	}
+
+	expect := []struct {
+		key  string
+		line int
+	}{
+		{"server.port", 2},
+		{"server.mode", 3},
+		{"server.cache", 4},
+		{"servers.web.host", 6},
+		{"servers.web.host", 7},
+		{"client.timeout", 0},
+		{"unknown.key", 9},
+	}
+
+	if len(violations) != len(expect) {
+		t.Error("invalid number of violations", len(violations), len(expect))
+		t.Log(err)
+		return
+	}
+
+	for i, v := range violations {
+		if JoinKey(v.Key) != expect[i].key || v.Line != expect[i].line {
+			t.Error(i, "invalid violation", v)
+		}
+	}
+}
+
+func TestInvalidSchema(t *testing.T) {
+	for i, s := range []string{
+		"[server/port]\ntyp = int",
+		"[server/port]\ntype = integer",
+		"[server/port]\ntype = int\nmax = many",
+		"[server/~port(]\ntype = int",
+		"strict = maybe",
+	} {
+		if _, err := ReadSchema(bytes.NewBufferString(s)); err == nil {
+			t.Error(i, "failed to fail")
+		}
+	}
+}
+
+func TestDefaultedDropped(t *testing.T) {
+	s, err := ReadSchema(bytes.NewBufferString("[port]\ndefault = 8080\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Document{}
+	d.EnableHistory(0)
+	e := d.ApplyDefaults(s)[0]
+	d.Delete("port")
+	if d.IsDefault(e) || len(d.defaulted) != 0 {
+		t.Error("failed to drop defaulted entry")
+	}
+
+	d.Undo()
+	if !d.IsDefault(e) {
+		t.Error("failed to restore defaulted entry")
+	}
+}
+
+func TestApplyDefaultsDeleted(t *testing.T) {
+	s, err := ReadSchema(bytes.NewBufferString("[port]\ndefault = 8080\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Document{}
+	d.Append("port", "9000")
+	d.AppendDeleted("port")
+	if len(d.ApplyDefaults(s)) != 1 || d.Val("port") != "8080" {
+		t.Error("failed to apply default to deleted key")
+	}
+}
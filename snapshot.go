@@ -0,0 +1,182 @@
+package keyval
+
+// Snapshot is an immutable copy of a document, safe for concurrent reads. The snapshots taken from the same
+// document share the copies of the unchanged entries.
+type Snapshot struct {
+	orig    []*Entry
+	entries []*Entry
+	index   map[string][]int
+}
+
+func entryEq(left, right *Entry) bool {
+	if left == nil || right == nil {
+		return left == right
+	}
+
+	return KeyEq(left.Key, right.Key) && left.Val == right.Val && left.Comment == right.Comment &&
+		left.Deleted == right.Deleted
+}
+
+func (s *Snapshot) unchanged(entries []*Entry) bool {
+	if len(s.orig) != len(entries) {
+		return false
+	}
+
+	for i, e := range entries {
+		if s.orig[i] != e || !entryEq(e, s.entries[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Snapshot returns an immutable snapshot of the document. Snapshot is not safe to call concurrently, even on
+// an unchanged document.
+func (d *Document) Snapshot() *Snapshot {
+	prev := d.snapshot
+	if prev != nil && prev.unchanged(d.entries) {
+		return prev
+	}
+
+	shared := make(map[*Entry]*Entry)
+	if prev != nil {
+		for i, e := range prev.orig {
+			shared[e] = prev.entries[i]
+		}
+	}
+
+	s := &Snapshot{
+		orig:    make([]*Entry, len(d.entries)),
+		entries: make([]*Entry, len(d.entries)),
+		index:   make(map[string][]int)}
+	for i, e := range d.entries {
+		s.orig[i] = e
+		if f, ok := shared[e]; ok && entryEq(e, f) {
+			s.entries[i] = f
+		} else {
+			s.entries[i] = copyEntry(e)
+		}
+
+		if e != nil {
+			ks := JoinKey(e.Key)
+			s.index[ks] = append(s.index[ks], i)
+		}
+	}
+
+	d.snapshot = s
+	return s
+}
+
+func (s *Snapshot) Len() int {
+	return len(s.entries)
+}
+
+func (s *Snapshot) EntryAt(i int) *Entry {
+	if i < 0 || i >= len(s.entries) {
+		return nil
+	}
+
+	return copyEntry(s.entries[i])
+}
+
+func (s *Snapshot) Entries() []*Entry {
+	return s.Document().Entries()
+}
+
+// Document returns a modifiable copy of the snapshot.
+func (s *Snapshot) Document() *Document {
+	d := &Document{entries: s.entries}
+	return d.DeepCopy()
+}
+
+// the index groups the entries by the joined key, that can be ambiguous when the key parts contain '.'
+func (s *Snapshot) indexOf(key []string) []int {
+	var indexes []int
+	for _, i := range s.index[JoinKey(key)] {
+		if KeyEq(s.entries[i].Key, key) {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
+}
+
+func (s *Snapshot) entryOf(key []string) *Entry {
+	indexes := s.indexOf(key)
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	return s.entries[indexes[len(indexes)-1]]
+}
+
+func (s *Snapshot) KeysOf(key ...string) [][]string {
+	var keys [][]string
+	for _, e := range s.entries {
+		if e != nil && len(e.Key) > len(key) && KeyEq(key, e.Key[:len(key)]) {
+			k := make([]string, len(e.Key)-len(key))
+			copy(k, e.Key[len(key):])
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
+
+func (s *Snapshot) Keys() [][]string {
+	return s.KeysOf()
+}
+
+func (s *Snapshot) LookupOf(key ...string) (string, bool) {
+	e := s.entryOf(key)
+	if e == nil || e.Deleted {
+		return "", false
+	}
+
+	return e.Val, true
+}
+
+func (s *Snapshot) ValOf(key ...string) string {
+	v, _ := s.LookupOf(key...)
+	return v
+}
+
+func (s *Snapshot) ValsOf(key ...string) []string {
+	var vals []string
+	for _, i := range s.indexOf(key) {
+		if s.entries[i].Deleted {
+			vals = nil
+			continue
+		}
+
+		vals = append(vals, s.entries[i].Val)
+	}
+
+	return vals
+}
+
+func (s *Snapshot) CommentOf(key ...string) string {
+	e := s.entryOf(key)
+	if e == nil {
+		return ""
+	}
+
+	return e.Comment
+}
+
+func (s *Snapshot) Lookup(key string) (string, bool) {
+	return s.LookupOf(SplitKey(key)...)
+}
+
+func (s *Snapshot) Val(key string) string {
+	return s.ValOf(SplitKey(key)...)
+}
+
+func (s *Snapshot) Vals(key string) []string {
+	return s.ValsOf(SplitKey(key)...)
+}
+
+func (s *Snapshot) Comment(key string) string {
+	return s.CommentOf(SplitKey(key)...)
+}
@@ -0,0 +1,76 @@
+package keyval
+
+import "testing"
+
+func TestDeepCopy(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8080")
+	c := d.DeepCopy()
+	c.SetVal("server.port", "9000")
+	c.EntryAt(0).Key[0] = "client"
+	if d.Val("server.port") != "8080" {
+		t.Error("failed to copy entries")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8080")
+	d.Append("server.host", "a.example.org")
+	d.Append("server.host", "b.example.org")
+	d.SetComment("server.port", "listen port")
+
+	s := d.Snapshot()
+	d.SetVal("server.port", "9000")
+	d.EntryAt(1).Val = "c.example.org"
+	if s.Val("server.port") != "8080" || s.Comment("server.port") != "listen port" {
+		t.Error("snapshot changed")
+	}
+
+	hosts := s.Vals("server.host")
+	if len(hosts) != 2 || hosts[0] != "a.example.org" || hosts[1] != "b.example.org" {
+		t.Error("snapshot changed", hosts)
+	}
+
+	s.EntryAt(0).Val = "9001"
+	s.Entries()[0].Val = "9002"
+	s.Keys()[0][0] = "client"
+	if s.Val("server.port") != "8080" || s.Keys()[0][0] != "server" {
+		t.Error("snapshot changed")
+	}
+
+	if _, ok := s.Lookup("server.timeout"); ok {
+		t.Error("failed to lookup missing key")
+	}
+
+	sd := s.Document()
+	sd.SetVal("server.port", "9003")
+	if s.Val("server.port") != "8080" {
+		t.Error("snapshot changed")
+	}
+}
+
+func TestSnapshotSharing(t *testing.T) {
+	d := &Document{}
+	d.Append("server.port", "8080")
+	d.Append("server.host", "example.org")
+
+	s1 := d.Snapshot()
+	if d.Snapshot() != s1 {
+		t.Error("failed to reuse unchanged snapshot")
+	}
+
+	d.SetVal("server.port", "9000")
+	s2 := d.Snapshot()
+	if s2 == s1 || s2.Val("server.port") != "9000" || s1.Val("server.port") != "8080" {
+		t.Error("failed to create new snapshot")
+	}
+
+	if s2.entries[1] != s1.entries[1] {
+		t.Error("failed to share unchanged entry")
+	}
+
+	if s2.entries[0] == s1.entries[0] {
+		t.Error("changed entry shared")
+	}
+}
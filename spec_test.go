@@ -618,6 +618,19 @@ func TestRead(t *testing.T) {
 		false,
 		[]*Entry{{Val: " \tescaped whitespace \t"}},
 		io.EOF,
+	}, {
+
+		// A value consisting of only an escaped '-' marks the entry as deleted.
+		"a = \\-\n[b]\nc = \\- # comment\nd = -\ne = \\-f\ng = \\- \\-",
+		false,
+		[]*Entry{
+			{Key: []string{"a"}, Deleted: true},
+			{Key: []string{"b", "c"}, Deleted: true},
+			{Key: []string{"b", "d"}, Val: "-", Comment: "comment"},
+			{Key: []string{"b", "e"}, Val: "-f", Comment: "comment"},
+			{Key: []string{"b", "g"}, Val: "- -", Comment: "comment"},
+		},
+		io.EOF,
 	}} {
 		var innerReader io.Reader = bytes.NewBuffer([]byte(d.doc))
 		if d.infinite {
@@ -680,6 +693,11 @@ func TestRead(t *testing.T) {
 				return
 			}
 
+			if entry.Deleted != checkEntry.Deleted {
+				t.Error(i, j, "invalid entry deletion", entry.Deleted, checkEntry.Deleted)
+				return
+			}
+
 			if entry.Val != checkEntry.Val {
 				t.Error(i, j, "invalid entry value")
 				t.Log(entry.Val)
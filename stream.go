@@ -0,0 +1,433 @@
+package keyval
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultServerBufferSize = 1 << 10
+	DefaultRetryInterval    = 100 * time.Millisecond
+	DefaultMaxRetryInterval = 10 * time.Second
+)
+
+var ErrServerClosed = errors.New("server closed")
+
+// EntrySource is implemented by the types that provide a stream of entries, like EntryReader and Client.
+type EntrySource interface {
+	ReadEntry() (*Entry, error)
+}
+
+// Server streams the entries passed to WriteEntry to every connected client. Every connection has its own
+// EntryWriter, so that the clients connecting later get the right sections and comments applied to the keys.
+//
+// When InitialEntries is set, it is called for every new connection, and the returned entries are sent before
+// the ones written after the connection was accepted, e.g. the current state of a StateApplier. It is called
+// after the connection was registered, without holding the lock of the server, so the entries written
+// meanwhile are sent after the initial ones.
+//
+// BufferSize, when set, is used instead of DefaultServerBufferSize, as the maximum number of entries waiting
+// to be sent to a connection. When a client doesn't keep up with the stream, and its buffer is full, it is
+// disconnected.
+type Server struct {
+	InitialEntries func() []*Entry
+	BufferSize     int
+	mx             sync.Mutex
+	conns          map[*serverConn]bool
+	listeners      map[net.Listener]bool
+	closed         bool
+	wg             sync.WaitGroup
+}
+
+type serverConn struct {
+	conn      net.Conn
+	queue     chan *Entry
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (sc *serverConn) close() {
+	sc.closeOnce.Do(func() {
+		close(sc.done)
+		sc.conn.Close()
+	})
+}
+
+func (s *Server) init() {
+	if s.conns == nil {
+		s.conns = make(map[*serverConn]bool)
+		s.listeners = make(map[net.Listener]bool)
+	}
+}
+
+func (s *Server) remove(sc *serverConn) {
+	s.mx.Lock()
+	delete(s.conns, sc)
+	s.mx.Unlock()
+	sc.close()
+}
+
+func (s *Server) writeConn(sc *serverConn) {
+	defer s.wg.Done()
+	defer s.remove(sc)
+
+	var initial []*Entry
+	if s.InitialEntries != nil {
+		initial = s.InitialEntries()
+	}
+
+	bw := bufio.NewWriter(sc.conn)
+	w := NewEntryWriter(bw)
+	for _, e := range initial {
+		if err := w.WriteEntry(e); err != nil {
+			return
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case e, ok := <-sc.queue:
+			if !ok {
+				bw.Flush()
+				return
+			}
+
+			if err := w.WriteEntry(e); err != nil {
+				return
+			}
+
+			if len(sc.queue) > 0 {
+				continue
+			}
+
+			if err := bw.Flush(); err != nil {
+				return
+			}
+		case <-sc.done:
+			return
+		}
+	}
+}
+
+// the clients are not expected to send anything, reading only detects the closed connections
+func (s *Server) readConn(sc *serverConn) {
+	defer s.wg.Done()
+	io.Copy(io.Discard, sc.conn)
+	s.remove(sc)
+}
+
+// ServeConn starts streaming the entries to c. It doesn't block, and c is closed when the client disconnects,
+// or when the server is closed.
+func (s *Server) ServeConn(c net.Conn) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		c.Close()
+		return ErrServerClosed
+	}
+
+	s.init()
+	size := s.BufferSize
+	if size <= 0 {
+		size = DefaultServerBufferSize
+	}
+
+	sc := &serverConn{conn: c, queue: make(chan *Entry, size), done: make(chan struct{})}
+	s.conns[sc] = true
+	s.wg.Add(2)
+	go s.writeConn(sc)
+	go s.readConn(sc)
+	return nil
+}
+
+// Serve accepts the connections from l, until the server is closed, when it returns ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mx.Lock()
+	if s.closed {
+		s.mx.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+
+	s.init()
+	s.listeners[l] = true
+	s.mx.Unlock()
+
+	defer func() {
+		s.mx.Lock()
+		delete(s.listeners, l)
+		s.mx.Unlock()
+	}()
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			s.mx.Lock()
+			closed := s.closed
+			s.mx.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+
+			return err
+		}
+
+		if err := s.ServeConn(c); err != nil {
+			return err
+		}
+	}
+}
+
+// ListenAndServe listens on the network address, e.g. "tcp" and "localhost:9000", or "unix" and a socket path,
+// and calls Serve.
+func (s *Server) ListenAndServe(network, address string) error {
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// WriteEntry sends a copy of the entry to every connected client. It doesn't block on the clients.
+func (s *Server) WriteEntry(e *Entry) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return ErrServerClosed
+	}
+
+	c := copyEntry(e)
+	for sc := range s.conns {
+		select {
+		case sc.queue <- c:
+		default:
+			delete(s.conns, sc)
+			sc.close()
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) closeListeners() {
+	for l := range s.listeners {
+		l.Close()
+	}
+}
+
+// Shutdown stops accepting new connections, sends the pending entries to the clients, and closes the
+// connections. When ctx is done before the pending entries were sent, the connections are closed, and the
+// error of ctx is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mx.Lock()
+	if !s.closed {
+		s.closed = true
+		s.closeListeners()
+		for sc := range s.conns {
+			close(sc.queue)
+		}
+	}
+
+	s.mx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.Close()
+		return ctx.Err()
+	}
+}
+
+// Close closes the listeners and the connections immediately, discarding the pending entries.
+func (s *Server) Close() error {
+	s.mx.Lock()
+	s.closed = true
+	s.closeListeners()
+	for sc := range s.conns {
+		sc.close()
+	}
+
+	s.mx.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// Client reads the entries streamed by a Server, the same way as an EntryReader. When the connection is lost,
+// it reconnects, waiting between the attempts from RetryInterval, doubled on every failure up to
+// MaxRetryInterval. An entry partially received before the connection was lost is discarded.
+//
+// Dial is called with a context that is cancelled by Close. When OnConnect is set, it is called after every
+// successful connection, before reading the entries from it. After Close, ReadEntry returns io.EOF.
+type Client struct {
+	Dial             func(context.Context) (net.Conn, error)
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+	OnConnect        func()
+	mx               sync.Mutex
+	conn             net.Conn
+	reader           *EntryReader
+	wait             time.Duration
+	closed           bool
+	ctx              context.Context
+	cancel           func()
+}
+
+// NewClient creates a client connecting to the network address, e.g. "tcp" and "localhost:9000", or "unix"
+// and a socket path.
+func NewClient(network, address string) *Client {
+	return &Client{Dial: func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, address)
+	}}
+}
+
+func (c *Client) init() {
+	if c.ctx == nil {
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+	}
+}
+
+// nextWait increases the wait before the next connection attempt. It must be called holding the lock.
+func (c *Client) nextWait() {
+	min, max := c.RetryInterval, c.MaxRetryInterval
+	if min <= 0 {
+		min = DefaultRetryInterval
+	}
+
+	if max <= 0 {
+		max = DefaultMaxRetryInterval
+	}
+
+	c.wait *= 2
+	if c.wait < min {
+		c.wait = min
+	}
+
+	if c.wait > max {
+		c.wait = max
+	}
+}
+
+func (c *Client) connect() (*EntryReader, error) {
+	for {
+		c.mx.Lock()
+		c.init()
+		if c.closed {
+			c.mx.Unlock()
+			return nil, io.EOF
+		}
+
+		if c.reader != nil {
+			r := c.reader
+			c.mx.Unlock()
+			return r, nil
+		}
+
+		wait, ctx := c.wait, c.ctx
+		c.mx.Unlock()
+
+		if wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return nil, io.EOF
+			}
+		}
+
+		conn, err := c.Dial(ctx)
+		if err != nil {
+			c.mx.Lock()
+			c.nextWait()
+			c.mx.Unlock()
+			continue
+		}
+
+		c.mx.Lock()
+		if c.closed {
+			c.mx.Unlock()
+			conn.Close()
+			return nil, io.EOF
+		}
+
+		c.conn = conn
+		c.reader = NewEntryReader(conn)
+		r := c.reader
+		c.mx.Unlock()
+
+		if c.OnConnect != nil {
+			c.OnConnect()
+		}
+
+		return r, nil
+	}
+}
+
+func (c *Client) disconnect() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+	}
+
+	c.conn = nil
+	c.reader = nil
+	c.nextWait()
+}
+
+// ReadEntry returns the next entry from the server, connecting or reconnecting when necessary. It returns
+// io.EOF only after Close was called.
+func (c *Client) ReadEntry() (*Entry, error) {
+	for {
+		r, err := c.connect()
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := r.ReadEntry()
+		switch {
+		case err == nil && e != nil:
+			c.mx.Lock()
+			c.wait = 0
+			c.mx.Unlock()
+			return e, nil
+		case err == nil || err == io.ErrNoProgress:
+		default:
+			c.disconnect()
+		}
+	}
+}
+
+// Close closes the connection, and stops reconnecting. A blocked ReadEntry call returns io.EOF.
+func (c *Client) Close() error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.init()
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+	c.cancel()
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+
+	return nil
+}
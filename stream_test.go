@@ -0,0 +1,282 @@
+package keyval
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func pipeClient(s *Server) *Client {
+	return &Client{Dial: func(context.Context) (net.Conn, error) {
+		local, remote := net.Pipe()
+		if err := s.ServeConn(local); err != nil {
+			return nil, err
+		}
+
+		return remote, nil
+	}}
+}
+
+func readEntries(t *testing.T, c *Client, n int) []*Entry {
+	var entries []*Entry
+	for len(entries) < n {
+		e, err := c.ReadEntry()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+func checkEntries(t *testing.T, entries []*Entry, expected ...*Entry) {
+	if len(entries) != len(expected) {
+		t.Error("invalid number of entries", len(entries), len(expected))
+		return
+	}
+
+	for i, e := range entries {
+		if !entryEq(e, expected[i]) {
+			t.Error(i, "invalid entry", e, expected[i])
+		}
+	}
+}
+
+func TestServerPipe(t *testing.T) {
+	s := &Server{InitialEntries: func() []*Entry {
+		return []*Entry{{Key: []string{"server", "port"}, Val: "8080", Comment: "initial"}}
+	}}
+
+	defer s.Close()
+
+	c := pipeClient(s)
+	defer c.Close()
+
+	checkEntries(t, readEntries(t, c, 1), &Entry{Key: []string{"server", "port"}, Val: "8080", Comment: "initial"})
+
+	s.WriteEntry(&Entry{Key: []string{"server", "host"}, Val: "example.org"})
+	s.WriteEntry(&Entry{Key: []string{"server", "port"}, Deleted: true})
+
+	c2 := pipeClient(s)
+	defer c2.Close()
+	readEntries(t, c2, 1)
+
+	s.WriteEntry(&Entry{Key: []string{"client", "timeout"}, Val: "3s", Comment: "client"})
+
+	checkEntries(t, readEntries(t, c, 3),
+		&Entry{Key: []string{"server", "host"}, Val: "example.org"},
+		&Entry{Key: []string{"server", "port"}, Deleted: true},
+		&Entry{Key: []string{"client", "timeout"}, Val: "3s", Comment: "client"})
+
+	checkEntries(t, readEntries(t, c2, 1), &Entry{Key: []string{"client", "timeout"}, Val: "3s", Comment: "client"})
+}
+
+func TestServerReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := l.Addr().String()
+	a := NewStateApplier(nil)
+	initial := func() []*Entry { return a.Snapshot().Entries() }
+
+	s := &Server{InitialEntries: initial}
+	go s.Serve(l)
+
+	connected := make(chan struct{}, 4)
+	c := NewClient("tcp", addr)
+	c.RetryInterval = 5 * time.Millisecond
+	c.OnConnect = func() { connected <- struct{}{} }
+
+	ca := NewStateApplier(nil)
+	applied := make(chan Change, 16)
+	ca.Subscribe(func(ch Change) { applied <- ch })
+	done := make(chan error)
+	go func() { done <- ca.ApplyAll(c) }()
+
+	receive := func() Change {
+		select {
+		case ch := <-applied:
+			return ch
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout")
+			return Change{}
+		}
+	}
+
+	<-connected
+	a.Apply(&Entry{Key: []string{"a"}, Val: "1"})
+	s.WriteEntry(&Entry{Key: []string{"a"}, Val: "1"})
+	if ch := receive(); ch.Type != ChangeInsert || ch.New != "1" {
+		t.Error("invalid change", ch)
+	}
+
+	s.Close()
+	a.Apply(&Entry{Key: []string{"a"}, Val: "2"})
+
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s = &Server{InitialEntries: initial}
+	defer s.Close()
+	go s.Serve(l)
+
+	<-connected
+	if ch := receive(); ch.Type != ChangeVal || ch.New != "2" {
+		t.Error("invalid change", ch)
+	}
+
+	c.Close()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServerUnix(t *testing.T) {
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "keyval.sock"))
+	if err != nil {
+		t.Skip(err)
+	}
+
+	s := &Server{InitialEntries: func() []*Entry { return []*Entry{{Key: []string{"a"}, Val: "1"}} }}
+	go s.Serve(l)
+
+	c := NewClient("unix", l.Addr().String())
+	checkEntries(t, readEntries(t, c, 1), &Entry{Key: []string{"a"}, Val: "1"})
+
+	s.WriteEntry(&Entry{Key: []string{"b"}, Val: "2"})
+	s.WriteEntry(&Entry{Key: []string{"c"}, Val: "3"})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	shutdown := make(chan error)
+	go func() { shutdown <- s.Shutdown(ctx) }()
+
+	checkEntries(t, readEntries(t, c, 2), &Entry{Key: []string{"b"}, Val: "2"}, &Entry{Key: []string{"c"}, Val: "3"})
+	if err := <-shutdown; err != nil {
+		t.Error(err)
+	}
+
+	if err := s.WriteEntry(&Entry{Key: []string{"d"}}); err != ErrServerClosed {
+		t.Error("failed to fail", err)
+	}
+
+	c.Close()
+	if _, err := c.ReadEntry(); err != io.EOF {
+		t.Error("failed to close client", err)
+	}
+}
+
+func TestServerSlowClient(t *testing.T) {
+	s := &Server{BufferSize: 1}
+	defer s.Close()
+
+	local, remote := net.Pipe()
+	s.ServeConn(local)
+	for i := 0; i < 3; i++ {
+		s.WriteEntry(&Entry{Key: []string{"a"}, Val: "1"})
+	}
+
+	remote.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err := io.Copy(io.Discard, remote); err != nil {
+		t.Error("failed to disconnect slow client", err)
+	}
+}
+
+func TestClientCloseWhileDialing(t *testing.T) {
+	c := &Client{Dial: func(ctx context.Context) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+
+	done := make(chan error)
+	go func() {
+		_, err := c.ReadEntry()
+		done <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	c.Close()
+	select {
+	case err := <-done:
+		if err != io.EOF {
+			t.Error("invalid error", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Error("failed to interrupt dialing")
+	}
+}
+
+func TestServerApplierFanOut(t *testing.T) {
+	a := NewStateApplier(nil)
+	s := &Server{InitialEntries: func() []*Entry { return a.Snapshot().Entries() }}
+	defer s.Close()
+	a.Subscribe(func(ch Change) { s.WriteEntry(ch.Entry) })
+
+	stop := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				a.Apply(&Entry{Key: []string{"a"}, Val: "done"})
+				return
+			default:
+				a.Apply(&Entry{Key: []string{"a"}, Val: strconv.Itoa(i)})
+			}
+		}
+	}()
+
+	const n = 8
+	connected := make(chan struct{}, n)
+	done := make(chan error, n)
+	for i := 0; i < n; i++ {
+		c := pipeClient(s)
+		defer c.Close()
+		go func() {
+			for first := true; ; first = false {
+				e, err := c.ReadEntry()
+				if err != nil {
+					done <- err
+					return
+				}
+
+				if first {
+					connected <- struct{}{}
+				}
+
+				if e.Val == "done" {
+					done <- nil
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-connected:
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	close(stop)
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Error(err)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout")
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package keyval
+
+import "sync"
+
+// SyncDocument wraps a document for concurrent use. Any number of readers can access the document at the same
+// time, while the writers are serialized and exclusive.
+type SyncDocument struct {
+	mx         sync.RWMutex
+	snapshotMx sync.Mutex
+	doc        *Document
+}
+
+func NewSyncDocument(d *Document) *SyncDocument {
+	if d == nil {
+		d = &Document{}
+	}
+
+	return &SyncDocument{doc: d}
+}
+
+// Read calls f with the wrapped document, holding a read lock. The document must not be modified by f, and
+// it, or the entries in it, must not be retained after f returns.
+func (s *SyncDocument) Read(f func(*Document)) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	f(s.doc)
+}
+
+// Write calls f with the wrapped document, holding the write lock, inside a transaction. When f returns an
+// error, or panics, the changes made by f are rolled back.
+func (s *SyncDocument) Write(f func(*Document) error) (err error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.doc.Begin()
+	completed := false
+	defer func() {
+		if !completed {
+			s.doc.Rollback()
+		}
+	}()
+
+	err = f(s.doc)
+	completed = true
+	if err != nil {
+		s.doc.Rollback()
+		return err
+	}
+
+	return s.doc.Commit()
+}
+
+// Snapshot returns an immutable snapshot of the document. When the document was not changed since the last
+// call, the same snapshot is returned.
+func (s *SyncDocument) Snapshot() *Snapshot {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	s.snapshotMx.Lock()
+	defer s.snapshotMx.Unlock()
+	return s.doc.Snapshot()
+}
+
+func (s *SyncDocument) DeepCopy() *Document {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.doc.DeepCopy()
+}
+
+func (s *SyncDocument) Entries() []*Entry {
+	return s.DeepCopy().Entries()
+}
+
+func (s *SyncDocument) Lookup(key string) (string, bool) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.doc.Lookup(key)
+}
+
+func (s *SyncDocument) Val(key string) string {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.doc.Val(key)
+}
+
+func (s *SyncDocument) Vals(key string) []string {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.doc.Vals(key)
+}
+
+func (s *SyncDocument) SetVal(key, val string) {
+	s.Write(func(d *Document) error {
+		d.SetVal(key, val)
+		return nil
+	})
+}
+
+func (s *SyncDocument) Append(key, val string) {
+	s.Write(func(d *Document) error {
+		d.Append(key, val)
+		return nil
+	})
+}
+
+func (s *SyncDocument) Delete(key string) {
+	s.Write(func(d *Document) error {
+		d.Delete(key)
+		return nil
+	})
+}
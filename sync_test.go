@@ -0,0 +1,101 @@
+package keyval
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSyncDocumentWriteRollback(t *testing.T) {
+	s := NewSyncDocument(nil)
+	s.Append("server.port", "8080")
+
+	errTest := errors.New("test")
+	err := s.Write(func(d *Document) error {
+		d.SetVal("server.port", "9000")
+		d.Append("server.host", "example.org")
+		return errTest
+	})
+
+	if err != errTest {
+		t.Error("failed to fail", err)
+	}
+
+	if s.Val("server.port") != "8080" || len(s.Entries()) != 1 {
+		t.Error("failed to roll back")
+	}
+}
+
+func TestSyncDocumentSnapshot(t *testing.T) {
+	s := NewSyncDocument(nil)
+	s.Append("server.port", "8080")
+	snapshot := s.Snapshot()
+	s.SetVal("server.port", "9000")
+	if snapshot.Val("server.port") != "8080" {
+		t.Error("snapshot changed")
+	}
+}
+
+func TestSyncDocumentConcurrent(t *testing.T) {
+	s := NewSyncDocument(nil)
+	s.Append("counter", "0")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Write(func(d *Document) error {
+					n, err := d.Int("counter")
+					if err != nil {
+						return err
+					}
+
+					d.SetVal("counter", strconv.Itoa(n+1))
+					return nil
+				})
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Read(func(d *Document) {
+					if _, err := d.Int("counter"); err != nil {
+						t.Error(err)
+					}
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+	if s.Val("counter") != "800" {
+		t.Error("invalid counter", s.Val("counter"))
+	}
+}
+
+func TestSyncWritePanic(t *testing.T) {
+	d := &Document{}
+	d.EnableHistory(0)
+	s := NewSyncDocument(d)
+
+	func() {
+		defer func() { recover() }()
+		s.Write(func(d *Document) error {
+			d.Append("a", "1")
+			panic("failed")
+		})
+	}()
+
+	if d.Len() != 0 {
+		t.Error("failed to roll back")
+	}
+
+	s.Append("b", "2")
+	if !d.CanUndo() || d.Commit() != ErrNoTransaction {
+		t.Error("transaction left open")
+	}
+}
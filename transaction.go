@@ -0,0 +1,145 @@
+package keyval
+
+import "errors"
+
+type op struct {
+	undo func()
+	redo func()
+}
+
+type history struct {
+	limit  int
+	done   [][]op
+	undone [][]op
+}
+
+var ErrNoTransaction = errors.New("no transaction")
+
+// recording tells whether the changes need to be recorded, for the history or for an open transaction
+func (d *Document) recording() bool {
+	return d.history != nil || len(d.tx) > 0
+}
+
+func (d *Document) record(o op) {
+	if len(d.tx) > 0 {
+		d.tx[len(d.tx)-1] = append(d.tx[len(d.tx)-1], o)
+		return
+	}
+
+	d.pushHistory([]op{o})
+}
+
+func (d *Document) pushHistory(ops []op) {
+	if d.history == nil || len(ops) == 0 {
+		return
+	}
+
+	d.history.done = append(d.history.done, ops)
+	d.history.undone = nil
+	if d.history.limit > 0 && len(d.history.done) > d.history.limit {
+		d.history.done = d.history.done[len(d.history.done)-d.history.limit:]
+	}
+}
+
+func undoOps(ops []op) {
+	for i := len(ops) - 1; i >= 0; i-- {
+		ops[i].undo()
+	}
+}
+
+func redoOps(ops []op) {
+	for _, o := range ops {
+		o.redo()
+	}
+}
+
+// Begin starts a transaction. Transactions can be nested, and the changes of a committed inner transaction
+// become part of the outer one.
+func (d *Document) Begin() {
+	d.tx = append(d.tx, nil)
+}
+
+func (d *Document) popTx() ([]op, error) {
+	if len(d.tx) == 0 {
+		return nil, ErrNoTransaction
+	}
+
+	ops := d.tx[len(d.tx)-1]
+	d.tx = d.tx[:len(d.tx)-1]
+	return ops, nil
+}
+
+// Commit completes the current transaction. When history is enabled, the changes of the outermost transaction
+// are undone and redone as a single step.
+func (d *Document) Commit() error {
+	ops, err := d.popTx()
+	if err != nil {
+		return err
+	}
+
+	if len(d.tx) > 0 {
+		d.tx[len(d.tx)-1] = append(d.tx[len(d.tx)-1], ops...)
+		return nil
+	}
+
+	d.pushHistory(ops)
+	d.autoCompact()
+	return nil
+}
+
+// Rollback reverts the changes made since the start of the current transaction.
+func (d *Document) Rollback() error {
+	ops, err := d.popTx()
+	if err != nil {
+		return err
+	}
+
+	undoOps(ops)
+	return nil
+}
+
+// EnableHistory starts recording the changes for Undo and Redo, keeping at most limit steps. When limit is 0
+// or less, the number of steps is not limited.
+func (d *Document) EnableHistory(limit int) {
+	d.history = &history{limit: limit}
+}
+
+func (d *Document) DisableHistory() {
+	d.history = nil
+}
+
+func (d *Document) CanUndo() bool {
+	return d.history != nil && len(d.tx) == 0 && len(d.history.done) > 0
+}
+
+func (d *Document) CanRedo() bool {
+	return d.history != nil && len(d.tx) == 0 && len(d.history.undone) > 0
+}
+
+// Undo reverts the last change or committed transaction. It returns false when there is nothing to undo, or
+// a transaction is in progress.
+func (d *Document) Undo() bool {
+	if !d.CanUndo() {
+		return false
+	}
+
+	h := d.history
+	ops := h.done[len(h.done)-1]
+	h.done = h.done[:len(h.done)-1]
+	undoOps(ops)
+	h.undone = append(h.undone, ops)
+	return true
+}
+
+func (d *Document) Redo() bool {
+	if !d.CanRedo() {
+		return false
+	}
+
+	h := d.history
+	ops := h.undone[len(h.undone)-1]
+	h.undone = h.undone[:len(h.undone)-1]
+	redoOps(ops)
+	h.done = append(h.done, ops)
+	return true
+}
@@ -0,0 +1,132 @@
+package keyval
+
+import "testing"
+
+func checkVals(t *testing.T, d *Document, vals ...string) {
+	if d.Len() != len(vals) {
+		t.Error("invalid number of entries", d.Len(), len(vals))
+		return
+	}
+
+	for i, v := range vals {
+		if d.EntryAt(i).Val != v {
+			t.Error(i, "invalid value", d.EntryAt(i).Val, v)
+		}
+	}
+}
+
+func TestRollback(t *testing.T) {
+	d := &Document{}
+	d.Append("a", "1")
+	d.Append("b", "2")
+	d.Append("c", "3")
+
+	d.Begin()
+	d.SetVal("a", "4")
+	d.Delete("b")
+	d.Append("d", "5")
+	d.SetComment("c", "comment")
+	d.TruncateStart(1)
+	if err := d.Rollback(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	checkVals(t, d, "1", "2", "3")
+	if d.Comment("c") != "" {
+		t.Error("failed to roll back comment")
+	}
+
+	if err := d.Rollback(); err != ErrNoTransaction {
+		t.Error("failed to fail", err)
+	}
+
+	if err := d.Commit(); err != ErrNoTransaction {
+		t.Error("failed to fail", err)
+	}
+}
+
+func TestNestedTransaction(t *testing.T) {
+	d := &Document{}
+	d.Append("a", "1")
+
+	d.Begin()
+	d.Append("b", "2")
+	d.Begin()
+	d.Append("c", "3")
+	d.Commit()
+	d.Begin()
+	d.Append("d", "4")
+	d.Rollback()
+	checkVals(t, d, "1", "2", "3")
+
+	d.Rollback()
+	checkVals(t, d, "1")
+}
+
+func TestUndoRedo(t *testing.T) {
+	d := &Document{}
+	d.Append("a", "1")
+	d.EnableHistory(0)
+
+	d.Append("b", "2")
+	d.SetVal("a", "3")
+	d.Begin()
+	d.Append("c", "4")
+	d.Delete("b")
+	d.Commit()
+	checkVals(t, d, "3", "4")
+
+	for _, vals := range [][]string{{"3", "2"}, {"1", "2"}, {"1"}} {
+		if !d.Undo() {
+			t.Error("failed to undo")
+			return
+		}
+
+		checkVals(t, d, vals...)
+	}
+
+	if d.Undo() {
+		t.Error("failed to stop undo")
+	}
+
+	for _, vals := range [][]string{{"1", "2"}, {"3", "2"}, {"3", "4"}} {
+		if !d.Redo() {
+			t.Error("failed to redo")
+			return
+		}
+
+		checkVals(t, d, vals...)
+	}
+
+	if d.Redo() {
+		t.Error("failed to stop redo")
+	}
+
+	d.Undo()
+	d.Append("e", "5")
+	if d.CanRedo() {
+		t.Error("failed to clear redo")
+	}
+
+	checkVals(t, d, "3", "2", "5")
+}
+
+func TestHistoryLimit(t *testing.T) {
+	d := &Document{}
+	d.EnableHistory(2)
+	d.Append("a", "1")
+	d.Append("b", "2")
+	d.Append("c", "3")
+
+	if !d.Undo() || !d.Undo() || d.Undo() {
+		t.Error("failed to limit history")
+	}
+
+	checkVals(t, d, "1")
+
+	d.Begin()
+	if d.CanUndo() || d.Undo() {
+		t.Error("failed to prevent undo in transaction")
+	}
+}
@@ -0,0 +1,301 @@
+package keyval
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type KeyError struct {
+	Key []string
+	Val string
+	Err error
+}
+
+var (
+	ErrMissingKey  = errors.New("missing key")
+	ErrInvalidSize = errors.New("invalid size")
+)
+
+// decimal units are powers of 1000, binary units are powers of 1024
+var sizeUnits = []struct {
+	suffix string
+	mul    float64
+}{
+	{"kib", 1 << 10},
+	{"mib", 1 << 20},
+	{"gib", 1 << 30},
+	{"tib", 1 << 40},
+	{"kb", 1e3},
+	{"mb", 1e6},
+	{"gb", 1e9},
+	{"tb", 1e12},
+	{"k", 1e3},
+	{"m", 1e6},
+	{"g", 1e9},
+	{"t", 1e12},
+	{"b", 1},
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("%s: %v", JoinKey(e.Key), e.Err)
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "yes", "on":
+		return true, nil
+	case "no", "off":
+		return false, nil
+	default:
+		return strconv.ParseBool(s)
+	}
+}
+
+func parseSize(s string) (int64, error) {
+	ls := strings.ToLower(s)
+	mul := float64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(ls, u.suffix) {
+			ls = strings.TrimSpace(ls[:len(ls)-len(u.suffix)])
+			mul = u.mul
+			break
+		}
+	}
+
+	// float64(math.MaxInt64) rounds up to 2^63, which is already out of range
+	f, err := strconv.ParseFloat(ls, 64)
+	if err != nil || math.IsNaN(f) || f < 0 || f*mul >= math.MaxInt64 {
+		return 0, ErrInvalidSize
+	}
+
+	return int64(f * mul), nil
+}
+
+func (d *Document) LookupOf(key ...string) (string, bool) {
+	e := d.effectiveEntry(key...)
+	if e == nil {
+		return "", false
+	}
+
+	return e.Val, true
+}
+
+func (d *Document) Lookup(key string) (string, bool) {
+	return d.LookupOf(SplitKey(key)...)
+}
+
+// parseOf calls parse with the effective value of the key. When the key is missing, the returned error wraps
+// ErrMissingKey.
+func (d *Document) parseOf(key []string, parse func(string) error) error {
+	v, ok := d.LookupOf(key...)
+	if !ok {
+		return &KeyError{Key: key, Err: ErrMissingKey}
+	}
+
+	if err := parse(v); err != nil {
+		return &KeyError{Key: key, Val: v, Err: err}
+	}
+
+	return nil
+}
+
+func (d *Document) IntOf(key ...string) (int, error) {
+	var v int
+	err := d.parseOf(key, func(s string) (err error) {
+		v, err = strconv.Atoi(s)
+		return
+	})
+
+	return v, err
+}
+
+func (d *Document) Int64Of(key ...string) (int64, error) {
+	var v int64
+	err := d.parseOf(key, func(s string) (err error) {
+		v, err = strconv.ParseInt(s, 10, 64)
+		return
+	})
+
+	return v, err
+}
+
+func (d *Document) FloatOf(key ...string) (float64, error) {
+	var v float64
+	err := d.parseOf(key, func(s string) (err error) {
+		v, err = strconv.ParseFloat(s, 64)
+		return
+	})
+
+	return v, err
+}
+
+func (d *Document) BoolOf(key ...string) (bool, error) {
+	var v bool
+	err := d.parseOf(key, func(s string) (err error) {
+		v, err = parseBool(s)
+		return
+	})
+
+	return v, err
+}
+
+func (d *Document) DurationOf(key ...string) (time.Duration, error) {
+	var v time.Duration
+	err := d.parseOf(key, func(s string) (err error) {
+		v, err = time.ParseDuration(s)
+		return
+	})
+
+	return v, err
+}
+
+// TimeOf expects the value in RFC3339 format.
+func (d *Document) TimeOf(key ...string) (time.Time, error) {
+	var v time.Time
+	err := d.parseOf(key, func(s string) (err error) {
+		v, err = time.Parse(time.RFC3339, s)
+		return
+	})
+
+	return v, err
+}
+
+// SizeOf parses byte sizes like 512, 10MB or 1.5GiB. The decimal units (k, KB, M, MB...) are powers of 1000,
+// the binary units (KiB, MiB...) are powers of 1024.
+func (d *Document) SizeOf(key ...string) (int64, error) {
+	var v int64
+	err := d.parseOf(key, func(s string) (err error) {
+		v, err = parseSize(s)
+		return
+	})
+
+	return v, err
+}
+
+// StringsOf returns the values of all the entries with the key, each split at ',' and trimmed.
+func (d *Document) StringsOf(key ...string) []string {
+	var s []string
+	for _, v := range d.ValsOf(key...) {
+		for _, vi := range strings.Split(v, ",") {
+			if vi = strings.TrimSpace(vi); vi != "" {
+				s = append(s, vi)
+			}
+		}
+	}
+
+	return s
+}
+
+func (d *Document) Int(key string) (int, error) {
+	return d.IntOf(SplitKey(key)...)
+}
+
+func (d *Document) Int64(key string) (int64, error) {
+	return d.Int64Of(SplitKey(key)...)
+}
+
+func (d *Document) Float(key string) (float64, error) {
+	return d.FloatOf(SplitKey(key)...)
+}
+
+func (d *Document) Bool(key string) (bool, error) {
+	return d.BoolOf(SplitKey(key)...)
+}
+
+func (d *Document) Duration(key string) (time.Duration, error) {
+	return d.DurationOf(SplitKey(key)...)
+}
+
+func (d *Document) Time(key string) (time.Time, error) {
+	return d.TimeOf(SplitKey(key)...)
+}
+
+func (d *Document) Size(key string) (int64, error) {
+	return d.SizeOf(SplitKey(key)...)
+}
+
+func (d *Document) Strings(key string) []string {
+	return d.StringsOf(SplitKey(key)...)
+}
+
+// The Or variants return the default value, when the key is missing or the value is invalid.
+
+func (d *Document) ValOr(key string, def string) string {
+	if v, ok := d.Lookup(key); ok {
+		return v
+	}
+
+	return def
+}
+
+func (d *Document) IntOr(key string, def int) int {
+	if v, err := d.Int(key); err == nil {
+		return v
+	}
+
+	return def
+}
+
+func (d *Document) Int64Or(key string, def int64) int64 {
+	if v, err := d.Int64(key); err == nil {
+		return v
+	}
+
+	return def
+}
+
+func (d *Document) FloatOr(key string, def float64) float64 {
+	if v, err := d.Float(key); err == nil {
+		return v
+	}
+
+	return def
+}
+
+func (d *Document) BoolOr(key string, def bool) bool {
+	if v, err := d.Bool(key); err == nil {
+		return v
+	}
+
+	return def
+}
+
+func (d *Document) DurationOr(key string, def time.Duration) time.Duration {
+	if v, err := d.Duration(key); err == nil {
+		return v
+	}
+
+	return def
+}
+
+func (d *Document) TimeOr(key string, def time.Time) time.Time {
+	if v, err := d.Time(key); err == nil {
+		return v
+	}
+
+	return def
+}
+
+func (d *Document) SizeOr(key string, def int64) int64 {
+	if v, err := d.Size(key); err == nil {
+		return v
+	}
+
+	return def
+}
+
+func (d *Document) StringsOr(key string, def []string) []string {
+	if v := d.Strings(key); len(v) > 0 {
+		return v
+	}
+
+	return def
+}
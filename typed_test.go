@@ -0,0 +1,138 @@
+package keyval
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const typedTestDoc = `[server]
+port = 8080
+ratio = 0.75
+debug = yes
+timeout = 3s
+started = 2016-03-01T12:00:00Z
+cache = 10MB
+buffer = 1.5KiB
+host = a.example.org, b.example.org
+host = c.example.org
+empty =
+invalid = eighty
+`
+
+func TestTyped(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(typedTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+
+	if v, err := d.Int("server.port"); err != nil || v != 8080 {
+		t.Error("failed to get int", v, err)
+	}
+
+	if v, err := d.Int64("server.port"); err != nil || v != 8080 {
+		t.Error("failed to get int64", v, err)
+	}
+
+	if v, err := d.Float("server.ratio"); err != nil || v != 0.75 {
+		t.Error("failed to get float", v, err)
+	}
+
+	if v, err := d.Bool("server.debug"); err != nil || !v {
+		t.Error("failed to get bool", v, err)
+	}
+
+	if v, err := d.Duration("server.timeout"); err != nil || v != 3*time.Second {
+		t.Error("failed to get duration", v, err)
+	}
+
+	if v, err := d.Time("server.started"); err != nil || !v.Equal(time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Error("failed to get time", v, err)
+	}
+
+	if v, err := d.Size("server.cache"); err != nil || v != 10000000 {
+		t.Error("failed to get size", v, err)
+	}
+
+	if v, err := d.Size("server.buffer"); err != nil || v != 1536 {
+		t.Error("failed to get size", v, err)
+	}
+
+	hosts := d.Strings("server.host")
+	if len(hosts) != 3 || hosts[1] != "b.example.org" || hosts[2] != "c.example.org" {
+		t.Error("failed to get strings", hosts)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(typedTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+
+	if v, ok := d.Lookup("server.empty"); !ok || v != "" {
+		t.Error("failed to lookup empty value")
+	}
+
+	if _, ok := d.Lookup("server.missing"); ok {
+		t.Error("failed to lookup missing value")
+	}
+
+	_, err := d.Int("server.missing")
+	if !errors.Is(err, ErrMissingKey) {
+		t.Error("failed to fail with missing key", err)
+	}
+
+	_, err = d.Int("server.invalid")
+	if kerr, ok := err.(*KeyError); !ok || !KeyEq(kerr.Key, []string{"server", "invalid"}) {
+		t.Error("failed to fail with key error", err)
+	} else if !strings.HasPrefix(err.Error(), "server.invalid:") {
+		t.Error("key path missing from error", err)
+	}
+
+	if _, err := d.Size("server.invalid"); !errors.Is(err, ErrInvalidSize) {
+		t.Error("failed to fail with invalid size", err)
+	}
+}
+
+func TestTypedDefaults(t *testing.T) {
+	d := &Document{}
+	if err := d.ReadAll(bytes.NewBufferString(typedTestDoc)); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+
+	if d.IntOr("server.port", 9000) != 8080 {
+		t.Error("failed to get existing value")
+	}
+
+	if d.IntOr("server.missing", 9000) != 9000 || d.IntOr("server.invalid", 9000) != 9000 {
+		t.Error("failed to get default")
+	}
+
+	if d.ValOr("server.empty", "default") != "" || d.ValOr("server.missing", "default") != "default" {
+		t.Error("failed to get string default")
+	}
+
+	if d.DurationOr("server.missing", time.Minute) != time.Minute {
+		t.Error("failed to get duration default")
+	}
+
+	if s := d.StringsOr("server.missing", []string{"x"}); len(s) != 1 || s[0] != "x" {
+		t.Error("failed to get strings default")
+	}
+}
+
+func TestSizeOutOfRange(t *testing.T) {
+	for _, s := range []string{"inf", "+Inf", "nan", "1e30", "9223372036854775808", "10000000tb"} {
+		if _, err := parseSize(s); err != ErrInvalidSize {
+			t.Error("failed to fail", s, err)
+		}
+	}
+
+	if v, err := parseSize("8tib"); err != nil || v != 8<<40 {
+		t.Error("invalid size", v, err)
+	}
+}
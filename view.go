@@ -0,0 +1,140 @@
+package keyval
+
+// View is a live view of the entries of a document under a key prefix. The keys passed to the accessors are
+// relative to the prefix, and the modifications are applied to the underlying document.
+type View struct {
+	doc    *Document
+	prefix []string
+}
+
+func (d *Document) Sub(prefix ...string) *View {
+	p := make([]string, len(prefix))
+	copy(p, prefix)
+	return &View{doc: d, prefix: p}
+}
+
+func (v *View) Sub(prefix ...string) *View {
+	return v.doc.Sub(v.key(prefix)...)
+}
+
+func (v *View) Prefix() []string {
+	p := make([]string, len(v.prefix))
+	copy(p, v.prefix)
+	return p
+}
+
+func (v *View) key(key []string) []string {
+	k := make([]string, 0, len(v.prefix)+len(key))
+	return append(append(k, v.prefix...), key...)
+}
+
+func (v *View) contains(e *Entry) bool {
+	return e != nil && len(e.Key) > len(v.prefix) && KeyEq(e.Key[:len(v.prefix)], v.prefix)
+}
+
+func (v *View) lastIndex() int {
+	entries := v.doc.Entries()
+	for i := len(entries) - 1; i >= 0; i-- {
+		if v.contains(entries[i]) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Entries returns copies of the entries under the prefix, with the keys relative to the prefix. Changing the
+// returned entries doesn't affect the document.
+func (v *View) Entries() []*Entry {
+	var entries []*Entry
+	for _, e := range v.doc.Entries() {
+		if v.contains(e) {
+			c := copyEntry(e)
+			c.Key = c.Key[len(v.prefix):]
+			entries = append(entries, c)
+		}
+	}
+
+	return entries
+}
+
+func (v *View) Len() int {
+	return len(v.Entries())
+}
+
+func (v *View) Document() *Document {
+	d := &Document{}
+	d.AppendEntry(v.Entries()...)
+	return d
+}
+
+func (v *View) KeysOf(key ...string) [][]string {
+	return v.doc.KeysOf(v.key(key)...)
+}
+
+func (v *View) Keys() [][]string {
+	return v.KeysOf()
+}
+
+func (v *View) ValOf(key ...string) string {
+	return v.doc.ValOf(v.key(key)...)
+}
+
+func (v *View) ValsOf(key ...string) []string {
+	return v.doc.ValsOf(v.key(key)...)
+}
+
+func (v *View) SetValOf(key []string, val string) {
+	v.doc.SetValOf(v.key(key), val)
+}
+
+// AppendVal inserts the entry after the last entry under the prefix, or at the end of the document, if there
+// is none.
+func (v *View) AppendVal(key []string, val string) {
+	at := v.lastIndex() + 1
+	if at == 0 {
+		at = v.doc.Len()
+	}
+
+	v.doc.InsertVal(at, v.key(key), val)
+}
+
+func (v *View) DeleteOf(key ...string) []*Entry {
+	return v.doc.DeleteOf(v.key(key)...)
+}
+
+func (v *View) CommentOf(key ...string) string {
+	return v.doc.CommentOf(v.key(key)...)
+}
+
+func (v *View) SetCommentOf(key []string, comment string) {
+	v.doc.SetCommentOf(v.key(key), comment)
+}
+
+func (v *View) Val(key string) string {
+	return v.ValOf(SplitKey(key)...)
+}
+
+func (v *View) Vals(key string) []string {
+	return v.ValsOf(SplitKey(key)...)
+}
+
+func (v *View) SetVal(key, val string) {
+	v.SetValOf(SplitKey(key), val)
+}
+
+func (v *View) Append(key string, val string) {
+	v.AppendVal(SplitKey(key), val)
+}
+
+func (v *View) Delete(key string) []*Entry {
+	return v.DeleteOf(SplitKey(key)...)
+}
+
+func (v *View) Comment(key string) string {
+	return v.CommentOf(SplitKey(key)...)
+}
+
+func (v *View) SetComment(key string, comment string) {
+	v.SetCommentOf(SplitKey(key), comment)
+}
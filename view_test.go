@@ -0,0 +1,55 @@
+package keyval
+
+import "testing"
+
+func TestView(t *testing.T) {
+	d := &Document{}
+	d.Append("server.http.port", "8080")
+	d.Append("server.http.host", "example.org")
+	d.Append("server.grpc.port", "9090")
+	d.Append("client.timeout", "3s")
+
+	v := d.Sub("server", "http")
+	if v.Val("port") != "8080" || v.Val("host") != "example.org" {
+		t.Error("failed to get values")
+	}
+
+	if v.Len() != 2 {
+		t.Error("invalid number of entries", v.Len())
+	}
+
+	entries := v.Entries()
+	entries[0].Key[0] = "changed"
+	if d.Val("server.http.port") != "8080" {
+		t.Error("entries not copied")
+	}
+
+	v.SetVal("port", "9000")
+	if d.Val("server.http.port") != "9000" {
+		t.Error("failed to set value in parent")
+	}
+
+	v.Append("timeout", "1s")
+	if d.EntryAt(2).Val != "1s" || d.Val("server.http.timeout") != "1s" {
+		t.Error("failed to append after the last entry of the view")
+	}
+
+	v.Delete("host")
+	if len(d.EntriesOf("server", "http", "host")) != 0 {
+		t.Error("failed to delete")
+	}
+
+	v.SetComment("port", "listen port")
+	if d.Comment("server.http.port") != "listen port" {
+		t.Error("failed to set comment")
+	}
+
+	if d.Sub("server").Sub("grpc").Val("port") != "9090" {
+		t.Error("failed to get nested view value")
+	}
+
+	d.Sub("cache").Append("size", "10MB")
+	if d.EntryAt(d.Len()-1).Val != "10MB" || d.Val("cache.size") != "10MB" {
+		t.Error("failed to append to empty view")
+	}
+}
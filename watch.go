@@ -0,0 +1,266 @@
+package keyval
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWatchInterval = time.Second
+	DefaultWatchDebounce = 300 * time.Millisecond
+)
+
+type WatchOptions struct {
+	// Interval, when set, is used instead of DefaultWatchInterval, to check the files for changes.
+	Interval time.Duration
+
+	// Debounce, when set, is used instead of DefaultWatchDebounce. The files are reloaded only when they
+	// didn't change for this duration, to avoid reading partially saved files.
+	Debounce time.Duration
+
+	// Schema, when set, is used to validate the reloaded document.
+	Schema *Schema
+
+	// IncludeKey, when set, is used instead of DefaultIncludeKey.
+	IncludeKey string
+}
+
+// WatchEvent is emitted after a reload. When the reload failed, Err is set, and the current document is not
+// changed.
+type WatchEvent struct {
+	Snapshot *Snapshot
+	Changed  [][]string
+	Err      error
+}
+
+type fileStat struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// Watcher keeps a document loaded from a file, and its included files, up to date. The files are polled for
+// changes, and the changed document is swapped in only when it could be read and it is valid.
+type Watcher struct {
+	options  WatchOptions
+	loader   *Loader
+	name     string
+	dir      string
+	mx       sync.Mutex
+	doc      *Document
+	snapshot *Snapshot
+	files    []string
+	patterns []string
+	subs     []*watchSubscription
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+type watchSubscription struct {
+	f func(WatchEvent)
+}
+
+// Watch loads the file at path, and starts watching it. The initial load and validation must succeed.
+func Watch(path string, o WatchOptions) (*Watcher, error) {
+	if o.Interval <= 0 {
+		o.Interval = DefaultWatchInterval
+	}
+
+	if o.Debounce <= 0 {
+		o.Debounce = DefaultWatchDebounce
+	}
+
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	w := &Watcher{
+		options: o,
+		loader:  &Loader{FS: os.DirFS(dir), IncludeKey: o.IncludeKey},
+		name:    filepath.ToSlash(name),
+		dir:     dir,
+		quit:    make(chan struct{}),
+		done:    make(chan struct{})}
+
+	d, state, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+
+	w.swap(d, state)
+	go w.run(w.stat())
+	return w, nil
+}
+
+func (w *Watcher) load() (*Document, *loadState, error) {
+	d := &Document{}
+	state := newLoadState()
+	if err := w.loader.load(d, w.name, nil, state); err != nil {
+		return nil, state, err
+	}
+
+	if w.options.Schema != nil {
+		if err := d.Validate(w.options.Schema); err != nil {
+			return nil, state, err
+		}
+	}
+
+	return d, state, nil
+}
+
+func (w *Watcher) watchFiles(state *loadState) {
+	w.files = state.files
+	w.patterns = state.patterns
+}
+
+func (w *Watcher) swap(d *Document, state *loadState) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	w.doc = d
+	w.snapshot = d.Snapshot()
+	w.watchFiles(state)
+}
+
+// stat checks the loaded files, and the files matching the include patterns, so that the files added to an
+// included directory are detected, too
+func (w *Watcher) stat() map[string]fileStat {
+	w.mx.Lock()
+	files, patterns := w.files, w.patterns
+	w.mx.Unlock()
+
+	for _, p := range patterns {
+		matches, _ := fs.Glob(w.loader.FS, p)
+		files = append(files[:len(files):len(files)], matches...)
+	}
+
+	s := make(map[string]fileStat)
+	for _, f := range files {
+		fi, err := os.Stat(filepath.Join(w.dir, filepath.FromSlash(f)))
+		if err != nil {
+			s[f] = fileStat{}
+			continue
+		}
+
+		s[f] = fileStat{exists: true, size: fi.Size(), modTime: fi.ModTime()}
+	}
+
+	return s
+}
+
+func statChanged(left, right map[string]fileStat) bool {
+	if len(left) != len(right) {
+		return true
+	}
+
+	for f, s := range left {
+		if rs, ok := right[f]; !ok || rs.exists != s.exists || rs.size != s.size || !rs.modTime.Equal(s.modTime) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *Watcher) emit(e WatchEvent) {
+	w.mx.Lock()
+	subs := w.subs
+	w.mx.Unlock()
+
+	for _, s := range subs {
+		s.f(e)
+	}
+}
+
+func (w *Watcher) reload() {
+	d, state, err := w.load()
+	if err != nil {
+		if len(state.files) > 0 {
+			w.mx.Lock()
+			w.watchFiles(state)
+			w.mx.Unlock()
+		}
+
+		w.emit(WatchEvent{Snapshot: w.Snapshot(), Err: err})
+		return
+	}
+
+	w.mx.Lock()
+	previous := w.doc
+	w.mx.Unlock()
+
+	changed := previous.ChangedKeys(d)
+	w.swap(d, state)
+	if len(changed) > 0 {
+		w.emit(WatchEvent{Snapshot: w.Snapshot(), Changed: changed})
+	}
+}
+
+func (w *Watcher) run(seen map[string]fileStat) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+
+	var (
+		pending    bool
+		lastChange time.Time
+	)
+
+	for {
+		select {
+		case <-w.quit:
+			return
+		case now := <-ticker.C:
+			current := w.stat()
+			if statChanged(seen, current) {
+				seen = current
+				lastChange = now
+				pending = true
+				continue
+			}
+
+			if pending && now.Sub(lastChange) >= w.options.Debounce {
+				pending = false
+				w.reload()
+				seen = w.stat()
+			}
+		}
+	}
+}
+
+// Snapshot returns the current state of the watched document.
+func (w *Watcher) Snapshot() *Snapshot {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.snapshot
+}
+
+// Subscribe calls f from the watching goroutine after every reload that changed the effective values, or that
+// failed. The returned function cancels the subscription.
+func (w *Watcher) Subscribe(f func(WatchEvent)) func() {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	s := &watchSubscription{f: f}
+	w.subs = append(w.subs, s)
+
+	return func() {
+		w.mx.Lock()
+		defer w.mx.Unlock()
+		for i, si := range w.subs {
+			if si == s {
+				w.subs = append(w.subs[:i:i], w.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Close stops watching, and waits until the watching goroutine exits.
+func (w *Watcher) Close() {
+	close(w.quit)
+	<-w.done
+}
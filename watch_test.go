@@ -0,0 +1,191 @@
+package keyval
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receiveEvent(t *testing.T, c <-chan WatchEvent) (WatchEvent, bool) {
+	select {
+	case e := <-c:
+		return e, true
+	case <-time.After(3 * time.Second):
+		t.Error("timeout")
+		return WatchEvent{}, false
+	}
+}
+
+func testWatch(t *testing.T, path string, o WatchOptions) (*Watcher, <-chan WatchEvent) {
+	o.Interval = 10 * time.Millisecond
+	o.Debounce = 30 * time.Millisecond
+	w, err := Watch(path, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan WatchEvent, 16)
+	w.Subscribe(func(e WatchEvent) { c <- e })
+	t.Cleanup(w.Close)
+	return w, c
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.k")
+	writeFile(t, path, "a = 1\nb = 2\n@include = inc.k\n")
+	writeFile(t, filepath.Join(dir, "inc.k"), "c = 3\n")
+
+	w, c := testWatch(t, path, WatchOptions{})
+	if w.Snapshot().Val("c") != "3" {
+		t.Error("failed to load include")
+	}
+
+	writeFile(t, path, "a = 1\nb = 42\n@include = inc.k\n")
+	e, ok := receiveEvent(t, c)
+	if !ok {
+		return
+	}
+
+	if e.Err != nil || len(e.Changed) != 1 || JoinKey(e.Changed[0]) != "b" || e.Snapshot.Val("b") != "42" {
+		t.Error("invalid event", e)
+	}
+
+	writeFile(t, filepath.Join(dir, "inc.k"), "d = 4\n")
+	e, ok = receiveEvent(t, c)
+	if !ok {
+		return
+	}
+
+	var changed []string
+	for _, k := range e.Changed {
+		changed = append(changed, JoinKey(k))
+	}
+
+	if strings.Join(changed, ",") != "d,c" {
+		t.Error("invalid changed keys", changed)
+	}
+
+	if w.Snapshot().Val("d") != "4" {
+		t.Error("failed to swap document")
+	}
+}
+
+func TestWatchRenameWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.k")
+	writeFile(t, path, "a = 1\n")
+
+	w, c := testWatch(t, path, WatchOptions{})
+
+	if err := os.Rename(path, path+"~"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(15 * time.Millisecond)
+	writeFile(t, path, "a = 22\n")
+
+	e, ok := receiveEvent(t, c)
+	if !ok {
+		return
+	}
+
+	if e.Err != nil || e.Snapshot.Val("a") != "22" {
+		t.Error("invalid event", e.Err)
+	}
+
+	if w.Snapshot().Val("a") != "22" {
+		t.Error("failed to swap document")
+	}
+
+	select {
+	case e := <-c:
+		t.Error("unexpected event", e)
+	case <-time.After(60 * time.Millisecond):
+	}
+}
+
+func TestWatchInvalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.k")
+	writeFile(t, path, "port = 8080\n")
+
+	s, err := ReadSchema(strings.NewReader("[port]\ntype = int\nrequired = true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, c := testWatch(t, path, WatchOptions{Schema: s})
+
+	writeFile(t, path, "port = eighty\n")
+	e, ok := receiveEvent(t, c)
+	if !ok {
+		return
+	}
+
+	if _, isValidation := e.Err.(ValidationError); !isValidation {
+		t.Error("failed to fail", e.Err)
+	}
+
+	if w.Snapshot().Val("port") != "8080" {
+		t.Error("failed to keep the valid document")
+	}
+
+	writeFile(t, path, "port = 9090\n")
+	e, ok = receiveEvent(t, c)
+	if !ok {
+		return
+	}
+
+	if e.Err != nil || w.Snapshot().Val("port") != "9090" {
+		t.Error("failed to reload", e.Err)
+	}
+
+	if _, err := Watch(filepath.Join(dir, "missing.k"), WatchOptions{}); err == nil {
+		t.Error("failed to fail")
+	}
+}
+
+func TestWatchIncludeDir(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.k")
+	if err := os.Mkdir(filepath.Join(dir, "conf.d"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, path, "a = 1\n@include = conf.d/*.k\n")
+	writeFile(t, filepath.Join(dir, "conf.d", "x.k"), "x = 1\n")
+
+	w, c := testWatch(t, path, WatchOptions{})
+
+	writeFile(t, filepath.Join(dir, "conf.d", "y.k"), "y = 2\n")
+	e, ok := receiveEvent(t, c)
+	if !ok {
+		return
+	}
+
+	if e.Err != nil || len(e.Changed) != 1 || JoinKey(e.Changed[0]) != "y" || w.Snapshot().Val("y") != "2" {
+		t.Error("invalid event", e)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "conf.d", "x.k")); err != nil {
+		t.Fatal(err)
+	}
+
+	e, ok = receiveEvent(t, c)
+	if !ok {
+		return
+	}
+
+	if _, found := w.Snapshot().Lookup("x"); e.Err != nil || found {
+		t.Error("failed to remove included file", e.Err)
+	}
+}
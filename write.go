@@ -215,6 +215,18 @@ func (w *EntryWriter) writeVal(val string, leadingSpace bool) error {
 	return w.write(escapeOutput([]byte(val), escapeVal, escapeBound, escapeBound)...)
 }
 
+// writeDeleted writes the tombstone marker in place of the value, which is an escaped '-', that is never
+// produced for a normal value.
+func (w *EntryWriter) writeDeleted(leadingSpace bool) error {
+	if leadingSpace {
+		if err := w.write(SpaceChar); err != nil {
+			return err
+		}
+	}
+
+	return w.write(StartValueChar, SpaceChar, EscapeChar, DeleteChar)
+}
+
 func (w *EntryWriter) WriteEntry(e *Entry) error {
 	withError := func(f ...func() error) {
 		for w.err == nil && len(f) > 0 {
@@ -247,7 +259,7 @@ func (w *EntryWriter) WriteEntry(e *Entry) error {
 	}
 
 	section, key := w.splitKey(e.Key)
-	if w.sectionChanged(section) && (len(key) != 0 || len(e.Val) != 0) {
+	if w.sectionChanged(section) && (len(key) != 0 || len(e.Val) != 0 || e.Deleted) {
 		w.section = section
 
 		if w.started && !commentWritten {
@@ -263,7 +275,11 @@ func (w *EntryWriter) WriteEntry(e *Entry) error {
 		keyWritten = true
 	}
 
-	if len(e.Val) > 0 {
+	switch {
+	case e.Deleted:
+		withError(func() error { return w.writeDeleted(keyWritten) })
+		valWritten = true
+	case len(e.Val) > 0:
 		withError(func() error { return w.writeVal(e.Val, keyWritten) })
 		valWritten = true
 	}